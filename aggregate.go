@@ -0,0 +1,138 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AggregateFunc is the function of an Aggregation
+type AggregateFunc string
+
+// Available aggregate functions
+const (
+	Sum     AggregateFunc = "SUM"
+	Min     AggregateFunc = "MIN"
+	Max     AggregateFunc = "MAX"
+	Avg     AggregateFunc = "AVG"
+	CountOf AggregateFunc = "COUNT"
+)
+
+// Aggregation is a function over a field of the root documents, the Name is the json name
+// of the result, the CountOf without Field counts the documents
+type Aggregation struct {
+	Name  string
+	Func  AggregateFunc
+	Field string
+}
+
+// AggregateQuery describes the Aggregate, the results are grouped by the GroupBy fields, they
+// are returned by the json name of their last part, the Filter's order and paging are ignored
+type AggregateQuery struct {
+	Filter       Filter
+	GroupBy      []string
+	Aggregations []Aggregation
+}
+
+// Count returns the number of the root documents of the type matching the filter,
+// the fields of the filter are json paths, the order and paging are ignored
+func (h *Handler) Count(ctx context.Context, typ string, filter Filter) (int64, error) {
+	conditions, params, err := h.findConditions(ctx, typ, nil, filter.Where, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	statement := fmt.Sprintf("SELECT RAW COUNT(*) FROM %s WHERE %s",
+		h.findFrom(typ, nil), strings.Join(conditions, " AND "))
+	rows, err := h.query(ctx, statement, params)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := rows.One(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Aggregate runs the aggregations over the root documents of the type and fills the container with
+// a result per group, the container should be *[]T where T has the json names of the results,
+// the fields of the query are json paths
+func (h *Handler) Aggregate(ctx context.Context, typ string, q AggregateQuery, container interface{}) error {
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return ErrInvalidAggregateContainer
+	}
+
+	statement, params, err := h.aggregateStatement(ctx, typ, q)
+	if err != nil {
+		return err
+	}
+	rows, err := h.query(ctx, statement, params)
+	if err != nil {
+		return err
+	}
+
+	slice := rv.Elem()
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	row := reflect.New(slice.Type().Elem())
+	for rows.Next(row.Interface()) {
+		result = reflect.Append(result, row.Elem())
+		row = reflect.New(slice.Type().Elem())
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	slice.Set(result)
+
+	return nil
+}
+
+// aggregateStatement builds the parameterised N1QL statement of the Aggregate
+func (h *Handler) aggregateStatement(ctx context.Context, typ string, q AggregateQuery) (string, []interface{}, error) {
+	if len(q.Aggregations) == 0 {
+		return "", nil, ErrInvalidAggregation
+	}
+	conditions, params, err := h.findConditions(ctx, typ, nil, q.Filter.Where, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var projections, groups []string
+	for _, field := range q.GroupBy {
+		path, err := fieldPath(nil, field, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		parts := strings.Split(field, ".")
+		projections = append(projections, fmt.Sprintf("%s AS `%s`", path, parts[len(parts)-1]))
+		groups = append(groups, path)
+	}
+	for _, a := range q.Aggregations {
+		if a.Name == "" || strings.Contains(a.Name, "`") {
+			return "", nil, ErrInvalidAggregation
+		}
+		var arg string
+		switch {
+		case a.Func == CountOf && a.Field == "":
+			arg = "*"
+		case a.Func == Sum || a.Func == Min || a.Func == Max || a.Func == Avg || a.Func == CountOf:
+			if arg, err = fieldPath(nil, a.Field, nil); err != nil {
+				return "", nil, err
+			}
+		default:
+			return "", nil, ErrInvalidAggregation
+		}
+		projections = append(projections, fmt.Sprintf("%s(%s) AS `%s`", a.Func, arg, a.Name))
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(projections, ", "), h.findFrom(typ, nil), strings.Join(conditions, " AND "))
+	if len(groups) > 0 {
+		statement += fmt.Sprintf(" GROUP BY %s ORDER BY %s", strings.Join(groups, ", "), strings.Join(groups, ", "))
+	}
+
+	return statement, params, nil
+}
@@ -0,0 +1,77 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	ctx := context.Background()
+	all, err := th.Count(ctx, "webshop", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processed, err := th.Count(ctx, "webshop", Filter{Where: []Predicate{{Field: "status", Op: Eq, Value: "processed"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, processed > 0)
+	assert.True(t, all >= processed)
+}
+
+func TestAggregate(t *testing.T) {
+	var totals []struct {
+		Status string  `json:"status"`
+		Orders int     `json:"orders"`
+		Total  int     `json:"total"`
+		Avg    float64 `json:"avg"`
+		Max    int     `json:"max"`
+	}
+	q := AggregateQuery{
+		Filter:  Filter{Where: []Predicate{{Field: "payment_method", Op: Eq, Value: "card"}}},
+		GroupBy: []string{"status"},
+		Aggregations: []Aggregation{
+			{Name: "orders", Func: CountOf},
+			{Name: "total", Func: Sum, Field: "final_grand_total"},
+			{Name: "avg", Func: Avg, Field: "final_grand_total"},
+			{Name: "max", Func: Max, Field: "final_grand_total"},
+		},
+	}
+	if err := th.Aggregate(context.Background(), "webshop", q, &totals); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEmpty(t, totals)
+	for _, total := range totals {
+		if total.Status == "processed" {
+			assert.Equal(t, total.Orders*443, total.Total)
+			assert.Equal(t, float64(443), total.Avg)
+			assert.Equal(t, 443, total.Max)
+		}
+	}
+}
+
+func TestAggregateStatement(t *testing.T) {
+	q := AggregateQuery{
+		GroupBy:      []string{"product.status"},
+		Aggregations: []Aggregation{{Name: "orders", Func: CountOf}, {Name: "total", Func: Sum, Field: "final_grand_total"}},
+	}
+	statement, _, err := th.aggregateStatement(context.Background(), "webshop", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT d.`product`.`status` AS `status`, COUNT(*) AS `orders`, SUM(d.`final_grand_total`) AS `total` FROM `" +
+		th.state.configuration.BucketName + "` AS d WHERE META(d).id LIKE $1 AND d._meta._type = $2 AND d._meta._deleted_at IS NOT VALUED " +
+		"GROUP BY d.`product`.`status` ORDER BY d.`product`.`status`"
+	assert.Equal(t, expected, statement)
+
+	_, _, err = th.aggregateStatement(context.Background(), "webshop", AggregateQuery{})
+	assert.Equal(t, ErrInvalidAggregation, err)
+	_, _, err = th.aggregateStatement(context.Background(), "webshop", AggregateQuery{Aggregations: []Aggregation{{Name: "total", Func: Sum}}})
+	assert.Equal(t, ErrUnknownField, err)
+	_, _, err = th.aggregateStatement(context.Background(), "webshop", AggregateQuery{Aggregations: []Aggregation{{Name: "total", Func: "MEDIAN", Field: "x"}}})
+	assert.Equal(t, ErrInvalidAggregation, err)
+}
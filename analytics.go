@@ -0,0 +1,128 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/couchbase/gocb"
+)
+
+// analyticsLink is the link of the analytics service to the local buckets
+const analyticsLink = "Local"
+
+// AnalyticsQuery runs the statement on the analytics service and decodes the rows into the container,
+// the container should be *[]T, the params are positional ($1, $2...) by []interface{} or named by
+// map[string]interface{}, with the AtPlus or RequestPlus consistency the datasets are updated first
+func (h *Handler) AnalyticsQuery(ctx context.Context, statement string, params interface{}, container interface{}) error {
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return ErrInvalidAnalyticsContainer
+	}
+	switch params.(type) {
+	case nil, []interface{}, map[string]interface{}:
+	default:
+		return ErrInvalidAnalyticsParams
+	}
+
+	rows, err := h.analytics(ctx, statement, params)
+	if err != nil {
+		return err
+	}
+	slice := rv.Elem()
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	row := reflect.New(slice.Type().Elem())
+	for rows.Next(row.Interface()) {
+		result = reflect.Append(result, row.Elem())
+		row = reflect.New(slice.Type().Elem())
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	slice.Set(result)
+
+	return nil
+}
+
+// CreateDataset creates the analytics dataset of the type named by the type, the dataset
+// shadows the root documents of the type filtered on its key prefix, the documents are
+// ingested after the ConnectAnalytics
+func (h *Handler) CreateDataset(ctx context.Context, typ string) error {
+	statement, err := h.datasetStatement(typ)
+	if err != nil {
+		return err
+	}
+	return h.analyticsExec(ctx, statement)
+}
+
+// CreateDatasets creates the datasets of the root document types and connects the analytics link,
+// the types are listed explicitly because the registered types contain the children too
+func (h *Handler) CreateDatasets(ctx context.Context, typs ...string) error {
+	for _, typ := range typs {
+		if err := h.CreateDataset(ctx, typ); err != nil {
+			return err
+		}
+	}
+	return h.ConnectAnalytics(ctx)
+}
+
+// DropDataset drops the analytics dataset of the type, the link stays connected for the
+// other datasets, dropping a dataset of a connected link needs Couchbase Server 6.5+
+func (h *Handler) DropDataset(ctx context.Context, typ string) error {
+	if err := validDatasetName(typ); err != nil {
+		return err
+	}
+	return h.analyticsExec(ctx, fmt.Sprintf("DROP DATASET `%s` IF EXISTS", typ))
+}
+
+// ConnectAnalytics connects the analytics link, the datasets start to ingest the documents
+func (h *Handler) ConnectAnalytics(ctx context.Context) error {
+	return h.analyticsExec(ctx, "CONNECT LINK "+analyticsLink)
+}
+
+// DisconnectAnalytics disconnects the analytics link, the datasets stop to ingest the documents
+func (h *Handler) DisconnectAnalytics(ctx context.Context) error {
+	return h.analyticsExec(ctx, "DISCONNECT LINK "+analyticsLink)
+}
+
+// analytics executes the statement on the analytics service with the configured timeout
+func (h *Handler) analytics(ctx context.Context, statement string, params interface{}) (gocb.AnalyticsResults, error) {
+	q := gocb.NewAnalyticsQuery(statement)
+	if timeout := h.state.configuration.Opts.AnalyticsTimeout; timeout.valid {
+		q.ServerSideTimeout(timeout.Value)
+	}
+	if scanConsistency(ctx) != NotBounded {
+		q.RawParam("scan_consistency", "request_plus")
+	}
+	return h.state.bucket.ExecuteAnalyticsQuery(q, params)
+}
+
+// analyticsExec executes the management statement on the analytics service
+func (h *Handler) analyticsExec(ctx context.Context, statement string) error {
+	rows, err := h.analytics(ctx, statement, nil)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// datasetStatement builds the statement creating the dataset of the type
+func (h *Handler) datasetStatement(typ string) (string, error) {
+	if err := validDatasetName(typ); err != nil {
+		return "", err
+	}
+	prefix, _ := json.Marshal(h.state.getType(typ) + "%")
+	name, _ := json.Marshal(typ)
+
+	return fmt.Sprintf("CREATE DATASET IF NOT EXISTS `%s` ON `%s` WHERE META().id LIKE %s AND %s = %s",
+		typ, h.state.configuration.BucketName, prefix, metaType, name), nil
+}
+
+func validDatasetName(typ string) error {
+	if typ == "" || strings.Contains(typ, "`") {
+		return ErrInvalidDataset
+	}
+	return nil
+}
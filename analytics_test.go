@@ -0,0 +1,47 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatasetStatement(t *testing.T) {
+	statement, err := th.datasetStatement("webshop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "CREATE DATASET IF NOT EXISTS `webshop` ON `"+th.state.configuration.BucketName+"` WHERE META().id LIKE \""+
+		th.state.getType("webshop")+"%\" AND _meta._type = \"webshop\"", statement)
+
+	_, err = th.datasetStatement("web`shop")
+	assert.Equal(t, ErrInvalidDataset, err)
+}
+
+func TestAnalyticsQueryInvalid(t *testing.T) {
+	var rows []webshop
+	err := th.AnalyticsQuery(context.Background(), "SELECT 1", nil, rows)
+	assert.Equal(t, ErrInvalidAnalyticsContainer, err)
+
+	err = th.AnalyticsQuery(context.Background(), "SELECT 1", "webshop", &rows)
+	assert.Equal(t, ErrInvalidAnalyticsParams, err)
+}
+
+func TestAnalyticsQuery(t *testing.T) {
+	ctx := WithConsistency(context.Background(), RequestPlus)
+	ws, _, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.CreateDatasets(ctx, "webshop"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens []string
+	err = th.AnalyticsQuery(ctx, "SELECT VALUE w.token FROM `webshop` w WHERE w.token = $1", []interface{}{ws.Token}, &tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{ws.Token}, tokens)
+}
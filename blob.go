@@ -0,0 +1,261 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/couchbase/gocb"
+	"github.com/rs/xid"
+)
+
+const (
+	blobKeyword = "blob"
+
+	// defaultBlobChunkSize is the size of the chunk documents without BlobChunkSize in the configuration
+	defaultBlobChunkSize = 1 << 20
+)
+
+// blobManifest describes a blob stored in chunk documents, the chunks of every
+// write get a new generation so the readers of the previous one aren't broken
+type blobManifest struct {
+	Generation     string    `json:"generation"`
+	Size           int64     `json:"size"`
+	ChunkSize      int       `json:"chunk_size"`
+	Checksum       string    `json:"checksum"`
+	ChunkChecksums []string  `json:"chunk_checksums"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PutBlob stores the data of the reader as the blob of a document, the data is split into
+// chunk documents with a manifest, every document gets the ttl, the previous blob is replaced,
+// the blob is removed by the Remove and the PurgeDeleted of its tree and touched by its Touch
+func (h *Handler) PutBlob(ctx context.Context, typ, id string, r io.Reader, ttl uint32) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+
+	manifest := blobManifest{
+		Generation: xid.New().String(),
+		ChunkSize:  h.blobChunkSize(),
+		CreatedAt:  time.Now().UTC(),
+	}
+	total := sha256.New()
+	buf := make([]byte, manifest.ChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			h.removeBlobChunks(ctx, typ, id, manifest)
+			return err
+		}
+
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			key := h.blobChunkKey(typ, id, manifest.Generation, len(manifest.ChunkChecksums))
+			_, token, err := h.state.bucket.UpsertMt(key, chunk, ttl)
+			if err != nil {
+				h.removeBlobChunks(ctx, typ, id, manifest)
+				return err
+			}
+			recordMutation(ctx, token)
+			sum := sha256.Sum256(chunk)
+			manifest.ChunkChecksums = append(manifest.ChunkChecksums, hex.EncodeToString(sum[:]))
+			manifest.Size += int64(n)
+			total.Write(chunk)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			h.removeBlobChunks(ctx, typ, id, manifest)
+			return err
+		}
+	}
+	manifest.Checksum = hex.EncodeToString(total.Sum(nil))
+
+	var previous blobManifest
+	_, err := h.state.bucket.Get(h.blobKey(typ, id), &previous)
+	if err != nil && err != gocb.ErrKeyNotFound {
+		h.removeBlobChunks(ctx, typ, id, manifest)
+		return err
+	}
+	_, token, err := h.state.bucket.UpsertMt(h.blobKey(typ, id), manifest, ttl)
+	if err != nil {
+		h.removeBlobChunks(ctx, typ, id, manifest)
+		return err
+	}
+	recordMutation(ctx, token)
+	h.removeBlobChunks(ctx, typ, id, previous)
+
+	return nil
+}
+
+// GetBlob returns a reader of the blob of a document, the checksums of the chunks
+// are verified while reading and the checksum of the whole blob at the end of it
+func (h *Handler) GetBlob(ctx context.Context, typ, id string) (io.ReadCloser, error) {
+	var manifest blobManifest
+	if _, err := h.state.bucket.Get(h.blobKey(typ, id), &manifest); err != nil {
+		return nil, err
+	}
+
+	return &blobReader{
+		ctx:      ctx,
+		h:        h,
+		typ:      typ,
+		id:       id,
+		manifest: manifest,
+		total:    sha256.New(),
+	}, nil
+}
+
+// RemoveBlob removes the blob of a document with its chunks
+func (h *Handler) RemoveBlob(ctx context.Context, typ, id string) error {
+	var manifest blobManifest
+	if _, err := h.state.bucket.Get(h.blobKey(typ, id), &manifest); err != nil {
+		return err
+	}
+	if err := h.remove(ctx, h.blobKey(typ, id)); err != nil {
+		return err
+	}
+	h.removeBlobChunks(ctx, typ, id, manifest)
+
+	return nil
+}
+
+// removeBlobChunks removes the chunks of a manifest, the missing chunks are ignored
+// because they could be expired and the others expire with their ttl anyway
+func (h *Handler) removeBlobChunks(ctx context.Context, typ, id string, manifest blobManifest) {
+	for i := range manifest.ChunkChecksums {
+		_ = h.remove(ctx, h.blobChunkKey(typ, id, manifest.Generation, i))
+	}
+}
+
+// blobKeys returns the keys of the manifests and the chunks of the blobs stored
+// next to the root and the children listed in the meta, read by their manifests
+func (h *Handler) blobKeys(typ, id string, m *meta) ([]string, error) {
+	var ops []gocb.BulkOp
+	var typs = []string{typ}
+	for _, child := range m.ChildDocuments {
+		typs = append(typs, child.Type)
+	}
+	for _, t := range typs {
+		ops = append(ops, &gocb.GetOp{Key: h.blobKey(t, id), Value: &blobManifest{}})
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for i, op := range ops {
+		getOp := op.(*gocb.GetOp)
+		if getOp.Err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if getOp.Err != nil {
+			return nil, getOp.Err
+		}
+		keys = append(keys, getOp.Key)
+		manifest := getOp.Value.(*blobManifest)
+		for n := range manifest.ChunkChecksums {
+			keys = append(keys, h.blobChunkKey(typs[i], id, manifest.Generation, n))
+		}
+	}
+
+	return keys, nil
+}
+
+// removeBlobs removes the blobs of the tree with their chunks
+func (h *Handler) removeBlobs(ctx context.Context, typ, id string, m *meta) error {
+	keys, err := h.blobKeys(typ, id, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := h.remove(ctx, key); err != nil && err != gocb.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) blobChunkSize() int {
+	if h.state.configuration.BlobChunkSize > 0 {
+		return h.state.configuration.BlobChunkSize
+	}
+	return defaultBlobChunkSize
+}
+
+func (h *Handler) blobKey(typ, id string) string {
+	return h.state.getDocumentKey(typ, id) + h.state.configuration.Separator + blobKeyword
+}
+
+func (h *Handler) blobChunkKey(typ, id, generation string, n int) string {
+	sep := h.state.configuration.Separator
+	return fmt.Sprintf("%s%s%s%s%d", h.blobKey(typ, id), sep, generation, sep, n)
+}
+
+// blobReader reads the chunks of a blob one by one
+type blobReader struct {
+	ctx      context.Context
+	h        *Handler
+	typ      string
+	id       string
+	manifest blobManifest
+
+	next   int
+	chunk  *bytes.Reader
+	total  hash.Hash
+	closed bool
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, ErrBlobClosed
+	}
+
+	for r.chunk == nil || r.chunk.Len() == 0 {
+		if r.next == len(r.manifest.ChunkChecksums) {
+			if hex.EncodeToString(r.total.Sum(nil)) != r.manifest.Checksum {
+				return 0, ErrBlobChecksum
+			}
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	return r.chunk.Read(p)
+}
+
+func (r *blobReader) readChunk() error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	var data []byte
+	key := r.h.blobChunkKey(r.typ, r.id, r.manifest.Generation, r.next)
+	if _, err := r.h.state.bucket.Get(key, &data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != r.manifest.ChunkChecksums[r.next] {
+		return ErrBlobChecksum
+	}
+
+	r.total.Write(data)
+	r.chunk = bytes.NewReader(data)
+	r.next++
+	return nil
+}
+
+func (r *blobReader) Close() error {
+	r.closed = true
+	r.chunk = nil
+	return nil
+}
@@ -0,0 +1,207 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocb"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func randomBlob(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPutBlob(t *testing.T) {
+	ctx := context.Background()
+	id := xid.New().String()
+	data := randomBlob(t, 2*defaultBlobChunkSize+512)
+
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader(data), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest blobManifest
+	if _, err := th.state.bucket.Get(th.blobKey("order", id), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(len(data)), manifest.Size)
+	assert.Len(t, manifest.ChunkChecksums, 3)
+
+	r, err := th.GetBlob(ctx, "order", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data, got)
+}
+
+func TestPutBlobOverwrite(t *testing.T) {
+	ctx := context.Background()
+	id := xid.New().String()
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader(randomBlob(t, defaultBlobChunkSize+1)), 0); err != nil {
+		t.Fatal(err)
+	}
+	var previous blobManifest
+	if _, err := th.state.bucket.Get(th.blobKey("order", id), &previous); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("invoice")
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader(data), 0); err != nil {
+		t.Fatal(err)
+	}
+	var chunk []byte
+	if _, err := th.state.bucket.Get(th.blobChunkKey("order", id, previous.Generation, 1), &chunk); err != gocb.ErrKeyNotFound {
+		t.Errorf("previous chunks should be removed, error: %v", err)
+	}
+
+	r, err := th.GetBlob(ctx, "order", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data, got)
+}
+
+func TestGetBlobChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	id := xid.New().String()
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader([]byte("invoice")), 0); err != nil {
+		t.Fatal(err)
+	}
+	var manifest blobManifest
+	if _, err := th.state.bucket.Get(th.blobKey("order", id), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.state.bucket.Replace(th.blobChunkKey("order", id, manifest.Generation, 0), []byte("tampered"), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := th.GetBlob(ctx, "order", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(r); err != ErrBlobChecksum {
+		t.Errorf("error should be %s instead of %v", ErrBlobChecksum, err)
+	}
+}
+
+func TestRemoveBlob(t *testing.T) {
+	ctx := context.Background()
+	id := xid.New().String()
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader([]byte("invoice")), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.RemoveBlob(ctx, "order", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.GetBlob(ctx, "order", id); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %v", gocb.ErrKeyNotFound, err)
+	}
+}
+
+func TestPutBlobWithConsistency(t *testing.T) {
+	ctx := WithConsistency(context.Background(), AtPlus)
+	id := xid.New().String()
+	if err := th.PutBlob(ctx, "order", id, bytes.NewReader([]byte("invoice")), 0); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, mutationState(ctx))
+}
+
+func TestPutBlobEmptyID(t *testing.T) {
+	if err := th.PutBlob(context.Background(), "order", "", bytes.NewReader(nil), 0); err != ErrEmptyID {
+		t.Errorf("error should be %s instead of %v", ErrEmptyID, err)
+	}
+}
+
+func TestRemoveWithBlob(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.PutBlob(ctx, "webshop", id, bytes.NewReader([]byte("invoice")), 0); err != nil {
+		t.Fatal(err)
+	}
+	var manifest blobManifest
+	if _, err := th.state.bucket.Get(th.blobKey("webshop", id), &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := th.Remove(ctx, "webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.GetBlob(ctx, "webshop", id); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %v", gocb.ErrKeyNotFound, err)
+	}
+	var chunk []byte
+	if _, err := th.state.bucket.Get(th.blobChunkKey("webshop", id, manifest.Generation, 0), &chunk); err != gocb.ErrKeyNotFound {
+		t.Errorf("chunks should be removed, error: %v", err)
+	}
+}
+
+func TestPurgeDeletedWithBlob(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.PutBlob(ctx, "soft_webshop", id, bytes.NewReader([]byte("invoice")), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.GetBlob(ctx, "soft_webshop", id); err != nil {
+		t.Errorf("blob should be kept until the purge, error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := th.PurgeDeleted(ctx, "soft_webshop", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.GetBlob(ctx, "soft_webshop", id); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %v", gocb.ErrKeyNotFound, err)
+	}
+}
+
+func TestTouchWithBlob(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.PutBlob(ctx, "webshop", id, bytes.NewReader(randomBlob(t, defaultBlobChunkSize+1)), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Touch(ctx, "webshop", id, &webshop{}, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := th.blobKeys("webshop", id, &meta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, keys, 3)
+	for _, key := range keys {
+		assert.NotZero(t, keyExpiry(t, key), key)
+	}
+}
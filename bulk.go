@@ -10,9 +10,14 @@ import (
 // GetBulk accepts a set of hits from a search
 // and a container represents the data-structure
 // and fill it up with the hits where the container
-// should be *[]T type
+// should be *[]T type with the length of the hits, the
+// slots of the soft deleted trees are left zero values
+// without IncludeDeleted, so they stay aligned with the hits
 func (h *Handler) GetBulk(ctx context.Context, hits []gocb.SearchResultHit, container interface{}) error {
 	var items []gocb.BulkOp
+	var values []interface{}
+	var roots []*rootReader
+	var rootKeys = make(map[string]bool)
 	rv := reflect.ValueOf(container)
 	if rv.Type().Kind() != reflect.Ptr {
 		return ErrInvalidBulkContainer
@@ -29,17 +34,41 @@ func (h *Handler) GetBulk(ctx context.Context, hits []gocb.SearchResultHit, cont
 			if err != nil {
 				return err
 			}
-			items = append(items, &gocb.GetOp{Key: hits[i].Id, Value: rvElem.Index(i).Addr().Interface()})
+			root := &rootReader{value: h.newDocumentReader(hits[i].Id, rvElem.Index(i).Addr().Interface())}
+			items = append(items, &gocb.GetOp{Key: hits[i].Id, Value: root})
+			values = append(values, root)
+			roots = append(roots, root)
+			rootKeys[hits[i].Id] = true
 			identifier := h.state.fetchDocumentIdentifier(hits[i].Id)
 			addressableFields := getStructAddressableSubfields(rvElem.Index(i).Addr())
 			for _, typ := range typs {
 				documentKey := h.state.getDocumentKey(typ, identifier)
-				items = append(items, &gocb.GetOp{Key: documentKey, Value: addressableFields[typ]})
+				value := h.newDocumentReader(documentKey, addressableFields[typ])
+				items = append(items, &gocb.GetOp{Key: documentKey, Value: value})
+				values = append(values, value)
 			}
 		}
 	default:
 		return ErrInvalidBulkContainer
 	}
 
-	return h.state.bucket.Do(items)
+	if err := h.state.bucket.Do(items); err != nil {
+		return err
+	}
+	if err := readError(items, rootKeys); err != nil {
+		return err
+	}
+	finishReaders(values...)
+
+	for i, root := range roots {
+		if root.deleted() && !includeDeleted(ctx) {
+			rvElem.Index(i).Set(reflect.Zero(rvElem.Type().Elem()))
+			continue
+		}
+		if err := afterGet(ctx, rvElem.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,313 @@
+package bucket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/couchbase/gocb"
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+	"gopkg.in/couchbase/gocbcore.v7"
+)
+
+// Codec is the format of the stored documents
+type Codec string
+
+// Available codecs, the documents stored with MessagePack or protobuf are binary documents
+// so the N1QL, FTS and sub-document operations can't use them, the protobuf codec
+// stores only protobuf message models without encrypted fields
+const (
+	CodecJSON     Codec = "json"
+	CodecMsgpack  Codec = "msgpack"
+	CodecProtobuf Codec = "protobuf"
+)
+
+const (
+	// common flags format of the binary documents
+	flagsBinary = 3 << 24
+	// flagsCodecMask is the part of the flags identifies the codec of the document
+	flagsCodecMask = 0xFF
+)
+
+// codec converts the JSON representation of a document to the stored format and back,
+// so the documents can be decoded into any value and the _meta stays readable
+type codec interface {
+	flag() uint32
+	fromJSON(data []byte) ([]byte, error)
+	toJSON(data []byte) ([]byte, error)
+}
+
+var codecs = map[Codec]codec{
+	CodecMsgpack:  msgpackCodec{},
+	CodecProtobuf: protobufCodec{},
+}
+
+// encodedDocument is the value passed to the write operations
+// when the document is stored with other codec than JSON
+type encodedDocument struct {
+	codec codec
+	value interface{}
+}
+
+// codec returns the codec of the document type, the type's own
+// codec has priority over the configuration's codec
+func (s *state) codec(name string) Codec {
+	if c := s.typeOptions(name).Codec; c != "" {
+		return c
+	}
+	if c := s.configuration.Codec; c != "" {
+		return c
+	}
+	return CodecJSON
+}
+
+// encode wraps the document into an encodedDocument if its type isn't stored as JSON
+func (h *Handler) encode(typ string, document interface{}) interface{} {
+	c := codecs[h.state.codec(typ)]
+	if c == nil {
+		return document
+	}
+	return &encodedDocument{codec: c, value: document}
+}
+
+// binary reports the documents of the type are stored as binary documents
+func (s *state) binary(name string) bool {
+	return s.codec(name) != CodecJSON
+}
+
+// transcoder encodes the encodedDocuments with their codec and decodes
+// the documents by their flags, everything else is handled by gocb's default
+type transcoder struct {
+	gocb.DefaultTranscoder
+}
+
+func (t transcoder) Encode(value interface{}) ([]byte, uint32, error) {
+	d, ok := value.(*encodedDocument)
+	if !ok {
+		return t.DefaultTranscoder.Encode(value)
+	}
+
+	data, err := json.Marshal(d.value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if data, err = d.codec.fromJSON(data); err != nil {
+		return nil, 0, err
+	}
+	return data, flagsBinary | d.codec.flag(), nil
+}
+
+func (t transcoder) Decode(data []byte, flags uint32, out interface{}) error {
+	c, ok := codecByFlags(flags)
+	if !ok {
+		return t.DefaultTranscoder.Decode(data, flags, out)
+	}
+
+	data, err := c.toJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &out)
+}
+
+func codecByFlags(flags uint32) (codec, bool) {
+	dataType, _ := gocbcore.DecodeCommonFlags(flags)
+	if dataType != gocbcore.BinaryType || flags&flagsCodecMask == 0 {
+		return nil, false
+	}
+	for _, c := range codecs {
+		if c.flag() == flags&flagsCodecMask {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) flag() uint32 {
+	return 1
+}
+
+func (msgpackCodec) fromJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(jsonNumbers(v))
+}
+
+func (msgpackCodec) toJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := msgpack.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := stringKeys(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonNumbers converts the json.Numbers to int64 or float64, so they are stored as MessagePack numbers
+func jsonNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = jsonNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = jsonNumbers(e)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	}
+	return v
+}
+
+// stringKeys converts the decoded MessagePack maps to map[string]interface{} which can be encoded as JSON
+func stringKeys(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		var m = make(map[string]interface{}, len(t))
+		for k, e := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, ErrInvalidMsgpackKey
+			}
+			converted, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = converted
+		}
+		return m, nil
+	case map[string]interface{}:
+		for k, e := range t {
+			converted, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = converted
+		}
+	case []interface{}:
+		for i, e := range t {
+			converted, err := stringKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = converted
+		}
+	}
+	return v, nil
+}
+
+// protobufCodec stores the protobuf message models by proto.Marshal next to their _meta, the
+// document starts with the length of the meta's JSON as uvarint followed by the meta and the
+// message, the type of the message is resolved by the _proto name of the meta
+type protobufCodec struct{}
+
+func (protobufCodec) flag() uint32 {
+	return 2
+}
+
+func (protobufCodec) fromJSON(data []byte) ([]byte, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	rawMeta, ok := document[metaFieldName]
+	if !ok {
+		return nil, ErrProtobufCodecModel
+	}
+	var m meta
+	if err := json.Unmarshal(rawMeta, &m); err != nil {
+		return nil, err
+	}
+	msg, err := newProtoMessage(m.Proto)
+	if err != nil {
+		return nil, err
+	}
+	if err := protoUnmarshaler.Unmarshal(bytes.NewReader(data), msg); err != nil {
+		return nil, err
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(rawMeta)+len(body))
+	n := binary.PutUvarint(out, uint64(len(rawMeta)))
+	out = append(out[:n], rawMeta...)
+	return append(out, body...), nil
+}
+
+func (protobufCodec) toJSON(data []byte) ([]byte, error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < l {
+		return nil, ErrInvalidProtobufDocument
+	}
+	rawMeta := data[n : n+int(l)]
+	var m meta
+	if err := json.Unmarshal(rawMeta, &m); err != nil {
+		return nil, err
+	}
+	msg, err := newProtoMessage(m.Proto)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(data[n+int(l):], msg); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := protoMarshaler.Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		return nil, err
+	}
+	document[metaFieldName] = rawMeta
+	return json.Marshal(document)
+}
+
+// protoTypes caches the message types by their names, so the messages not
+// registered by protoc-gen-go can be decoded after they were written or read
+var protoTypes sync.Map
+
+// registerProtoType caches the type of the message and returns its name, the registered
+// name of the message or the name of its Go type for the unregistered messages
+func registerProtoType(msg proto.Message) string {
+	rt := reflect.TypeOf(msg).Elem()
+	name := proto.MessageName(msg)
+	if name == "" {
+		name = rt.String()
+	}
+	protoTypes.LoadOrStore(name, rt)
+	return name
+}
+
+// newProtoMessage returns a new message of the type by its name
+func newProtoMessage(name string) (proto.Message, error) {
+	if name == "" {
+		return nil, ErrProtobufCodecModel
+	}
+	if rt, ok := protoTypes.Load(name); ok {
+		return reflect.New(rt.(reflect.Type)).Interface().(proto.Message), nil
+	}
+	if rt := proto.MessageType(name); rt != nil {
+		return reflect.New(rt.Elem()).Interface().(proto.Message), nil
+	}
+	return nil, ErrUnknownProtobufMessage
+}
@@ -0,0 +1,194 @@
+package bucket
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscoder(t *testing.T) {
+	var document = map[string]interface{}{
+		"status":      "processed",
+		"total":       443,
+		"items":       []interface{}{"book", 1.5, true, nil},
+		metaFieldName: &meta{Type: "webshop"},
+	}
+
+	for name, c := range codecs {
+		// the protobuf codec needs a message model, see TestProtobufCodec
+		if name == CodecProtobuf {
+			continue
+		}
+		t.Run(string(name), func(t *testing.T) {
+			data, flags, err := transcoder{}.Encode(&encodedDocument{codec: c, value: document})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, flagsBinary|c.flag(), flags)
+
+			var decoded map[string]interface{}
+			if err := (transcoder{}).Decode(data, flags, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "processed", decoded["status"])
+			assert.Equal(t, float64(443), decoded["total"])
+			assert.Equal(t, []interface{}{"book", 1.5, true, nil}, decoded["items"])
+
+			var m metaContainer
+			if err := (transcoder{}).Decode(data, flags, &m); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "webshop", m.Meta.Type)
+		})
+	}
+}
+
+func TestTranscoderDefault(t *testing.T) {
+	data, flags, err := transcoder{}.Encode(map[string]string{"status": "processed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := codecByFlags(flags); ok {
+		t.Error("JSON document shouldn't have codec")
+	}
+
+	var decoded map[string]string
+	if err := (transcoder{}).Decode(data, flags, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "processed", decoded["status"])
+
+	_, flags, err = transcoder{}.Encode([]byte("binary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := codecByFlags(flags); ok {
+		t.Error("binary value shouldn't have codec")
+	}
+}
+
+// protoAmount is written like the protoc-gen-go output of:
+//
+//	message Amount {
+//	    int64 value = 1;
+//	    string currency = 2;
+//	}
+type protoAmount struct {
+	Value                int64    `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	Currency             string   `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *protoAmount) Reset()         { *m = protoAmount{} }
+func (m *protoAmount) String() string { return proto.CompactTextString(m) }
+func (*protoAmount) ProtoMessage()    {}
+
+func TestProtobufCodec(t *testing.T) {
+	amount := &protoAmount{Value: 1<<53 + 1, Currency: "HUF"}
+	fields, err := protoFields(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields[metaFieldName] = &meta{Type: "amount", Proto: registerProtoType(amount)}
+
+	c := codecs[CodecProtobuf]
+	data, flags, err := transcoder{}.Encode(&encodedDocument{codec: c, value: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, flagsBinary|c.flag(), flags)
+
+	// the message is stored by its wire format after the meta
+	l, n := binary.Uvarint(data)
+	var stored protoAmount
+	if err := proto.Unmarshal(data[n+int(l):], &stored); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, proto.Equal(amount, &stored))
+
+	var decoded protoAmount
+	if err := (transcoder{}).Decode(data, flags, &protoReader{ptr: &decoded}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1<<53+1), decoded.Value)
+	assert.Equal(t, "HUF", decoded.Currency)
+
+	var m metaContainer
+	if err := (transcoder{}).Decode(data, flags, &m); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "amount", m.Meta.Type)
+
+	delete(fields, metaFieldName)
+	if _, _, err := (transcoder{}).Encode(&encodedDocument{codec: c, value: fields}); err != ErrProtobufCodecModel {
+		t.Errorf("error should be %s instead of %s", ErrProtobufCodecModel, err)
+	}
+}
+
+func TestInsertWithCodec(t *testing.T) {
+	ctx := context.Background()
+	ws := generate()
+	_, id, err := th.Insert(ctx, "msgpack_webshop", "", ws, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := webshop{}
+	if err := th.Get(ctx, "msgpack_webshop", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws, got)
+
+	m, err := th.getMeta("msgpack_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "msgpack_webshop", m.Type)
+}
+
+func TestInsertWithProtobufCodec(t *testing.T) {
+	ctx := context.Background()
+	account := generateProtoAccount()
+	_, id, err := th.Insert(ctx, "proto_codec_account", "", account, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got protoAccount
+	if err := th.Get(ctx, "proto_codec_account", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, proto.Equal(account, &got), "expected %v, got %v", account, &got)
+
+	m, err := th.getMeta("proto_codec_account", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "proto_codec_account", m.Type)
+	assert.Equal(t, "bucket.protoAccount", m.Proto)
+}
+
+func TestInsertWithProtobufCodecNonMessageExpectError(t *testing.T) {
+	h := testHandler(t, map[string]TypeOptions{"proto_webshop": {Codec: CodecProtobuf}})
+	if _, _, err := h.Insert(context.Background(), "proto_webshop", "", generate(), 0); err != ErrProtobufCodecModel {
+		t.Errorf("error should be %s instead of %s", ErrProtobufCodecModel, err)
+	}
+}
+
+func TestSoftRemoveWithCodecExpectError(t *testing.T) {
+	h := testHandler(t, map[string]TypeOptions{"msgpack_soft_webshop": {Codec: CodecMsgpack, SoftDelete: true}})
+
+	ctx := context.Background()
+	_, id, err := h.Insert(ctx, "msgpack_soft_webshop", "", generate(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Remove(ctx, "msgpack_soft_webshop", id, &webshop{}); err != ErrSubdocumentCodec {
+		t.Errorf("error should be %s instead of %s", ErrSubdocumentCodec, err)
+	}
+}
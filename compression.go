@@ -0,0 +1,26 @@
+package bucket
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compressionConnectionString adds the snappy datatype compression options of gocb to the connection
+// string by the CompressThreshold, the documents above it are sent compressed and the server keeps
+// them as JSON, so the N1QL, FTS, views, analytics and sub-document operations can use them
+func compressionConnectionString(c *Configuration) string {
+	var option string
+	switch {
+	case c.CompressThreshold > 0:
+		option = "compression=true&compression_min_size=" + strconv.Itoa(c.CompressThreshold)
+	case c.CompressThreshold < 0:
+		option = "compression=false"
+	default:
+		return c.ConnectionString
+	}
+
+	if strings.Contains(c.ConnectionString, "?") {
+		return c.ConnectionString + "&" + option
+	}
+	return c.ConnectionString + "?" + option
+}
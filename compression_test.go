@@ -0,0 +1,66 @@
+package bucket
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionConnectionString(t *testing.T) {
+	assert.Equal(t, "couchbase://localhost", compressionConnectionString(&Configuration{ConnectionString: "couchbase://localhost"}))
+	assert.Equal(t, "couchbase://localhost?compression=false",
+		compressionConnectionString(&Configuration{ConnectionString: "couchbase://localhost", CompressThreshold: -1}))
+	assert.Equal(t, "couchbase://localhost?kv_timeout=10&compression=true&compression_min_size=1",
+		compressionConnectionString(&Configuration{ConnectionString: "couchbase://localhost?kv_timeout=10", CompressThreshold: 1}))
+	assert.Equal(t, "couchbase://localhost?compression=true&compression_min_size=1024",
+		compressionConnectionString(&Configuration{ConnectionString: "couchbase://localhost", CompressThreshold: 1024}))
+	assert.Equal(t, "couchbase://localhost?kv_timeout=10&compression=false",
+		compressionConnectionString(&Configuration{ConnectionString: "couchbase://localhost?kv_timeout=10", CompressThreshold: -1}))
+}
+
+func TestInsertWithCompression(t *testing.T) {
+	var tests = []struct {
+		threshold int
+		option    string
+	}{
+		{threshold: 64, option: "compression=true&compression_min_size=64"},
+		{threshold: -1, option: "compression=false"},
+	}
+
+	for _, test := range tests {
+		conf := *th.state.configuration
+		conf.CompressThreshold = test.threshold
+		assert.Contains(t, compressionConnectionString(&conf), test.option)
+		h, err := New(&conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.Background()
+		ws := generate()
+		ws.Notes = strings.Repeat("long notes ", 1000)
+		_, id, err := h.Insert(ctx, "webshop", "", ws, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := webshop{}
+		if err := h.Get(ctx, "webshop", id, &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, ws, got)
+
+		// the compressed documents stay JSON for the sub-document operations
+		fragment, err := h.state.bucket.LookupIn(h.state.getDocumentKey("webshop", id)).Get("notes").Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var notes string
+		if err := fragment.Content("notes", &notes); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, ws.Notes, notes)
+	}
+}
@@ -0,0 +1,146 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/couchbase/gocb"
+)
+
+// ScanConsistency defines what the queries and searches wait for before they run
+type ScanConsistency int
+
+// Available scan consistencies
+const (
+	// NotBounded returns the already indexed documents without waiting
+	NotBounded ScanConsistency = iota
+	// AtPlus waits for the writes made with the context of the WithConsistency
+	AtPlus
+	// RequestPlus waits for every write made before the query, the searches wait AtPlus
+	RequestPlus
+)
+
+// consistencyState collects the mutation tokens of the writes made with a context
+type consistencyState struct {
+	mu     sync.Mutex
+	tokens *gocb.MutationState
+	empty  bool
+}
+
+// WithConsistency returns a context which collects the mutation tokens of the writes made with it,
+// the Find, Count, Aggregate and the searches with the context wait for the writes by the consistency
+func WithConsistency(ctx context.Context, consistency ScanConsistency) context.Context {
+	if consistencyFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, contextKeyConsistencyState, &consistencyState{tokens: gocb.NewMutationState(), empty: true})
+	}
+	return context.WithValue(ctx, contextKeyConsistency, consistency)
+}
+
+func consistencyFromContext(ctx context.Context) *consistencyState {
+	s, _ := ctx.Value(contextKeyConsistencyState).(*consistencyState)
+	return s
+}
+
+func scanConsistency(ctx context.Context) ScanConsistency {
+	c, _ := ctx.Value(contextKeyConsistency).(ScanConsistency)
+	return c
+}
+
+// recordMutation adds the mutation token of a write to the state of the context, the zero
+// tokens of the operations without one are skipped so they don't make the state non-empty
+func recordMutation(ctx context.Context, token gocb.MutationToken) {
+	if token == (gocb.MutationToken{}) {
+		return
+	}
+	if s := consistencyFromContext(ctx); s != nil {
+		s.mu.Lock()
+		s.tokens.Add(token)
+		s.empty = false
+		s.mu.Unlock()
+	}
+}
+
+// mutationState returns a copy of the collected tokens, so the writes can go on while
+// the query is sent, it returns nil when the context has no writes
+func mutationState(ctx context.Context) *gocb.MutationState {
+	s := consistencyFromContext(ctx)
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.empty {
+		return nil
+	}
+	data, err := json.Marshal(s.tokens)
+	if err != nil {
+		return nil
+	}
+	var state gocb.MutationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+// consistentQuery sets the consistency of the context on the query
+func consistentQuery(ctx context.Context, q *gocb.N1qlQuery) {
+	switch scanConsistency(ctx) {
+	case RequestPlus:
+		q.Consistency(gocb.RequestPlus)
+	case AtPlus:
+		if state := mutationState(ctx); state != nil {
+			q.ConsistentWith(state)
+		}
+	}
+}
+
+// consistentSearch sets the consistency of the context on the search, the
+// search service can wait only for the tokens so RequestPlus means AtPlus
+func consistentSearch(ctx context.Context, q *gocb.SearchQuery) {
+	if scanConsistency(ctx) == NotBounded {
+		return
+	}
+	if state := mutationState(ctx); state != nil {
+		q.ConsistentWith(state)
+	}
+}
+
+// doMt executes the write operations one by one for their mutation tokens,
+// the bulk operations of gocb don't return them
+func (h *Handler) doMt(ctx context.Context, ops []gocb.BulkOp) error {
+	for _, op := range ops {
+		var token gocb.MutationToken
+		switch o := op.(type) {
+		case *gocb.InsertOp:
+			o.Cas, token, o.Err = h.state.bucket.InsertMt(o.Key, o.Value, o.Expiry)
+		case *gocb.UpsertOp:
+			o.Cas, token, o.Err = h.state.bucket.UpsertMt(o.Key, o.Value, o.Expiry)
+		case *gocb.ReplaceOp:
+			o.Cas, token, o.Err = h.state.bucket.ReplaceMt(o.Key, o.Value, o.Cas, o.Expiry)
+		case *gocb.RemoveOp:
+			o.Cas, token, o.Err = h.state.bucket.RemoveMt(o.Key, o.Cas)
+		default:
+			if err := h.state.bucket.Do([]gocb.BulkOp{op}); err != nil {
+				return err
+			}
+		}
+		if err := bulkOpError(op); err != nil {
+			return err
+		}
+		recordMutation(ctx, token)
+	}
+	return nil
+}
+
+// remove removes a document and records its mutation token
+func (h *Handler) remove(ctx context.Context, key string) error {
+	_, token, err := h.state.bucket.RemoveMt(key, 0)
+	if err != nil {
+		return err
+	}
+	recordMutation(ctx, token)
+	return nil
+}
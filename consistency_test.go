@@ -0,0 +1,70 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/couchbase/gocb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithConsistency(t *testing.T) {
+	ctx := WithConsistency(context.Background(), AtPlus)
+	assert.Equal(t, AtPlus, scanConsistency(ctx))
+	assert.Nil(t, mutationState(ctx))
+	recordMutation(ctx, gocb.MutationToken{})
+	assert.Nil(t, mutationState(ctx))
+
+	state := consistencyFromContext(ctx)
+	ctx = WithConsistency(ctx, RequestPlus)
+	assert.Equal(t, RequestPlus, scanConsistency(ctx))
+	assert.True(t, state == consistencyFromContext(ctx))
+
+	assert.Equal(t, NotBounded, scanConsistency(context.Background()))
+	assert.Nil(t, mutationState(context.Background()))
+}
+
+func TestFindReadYourOwnWrites(t *testing.T) {
+	ctx := WithConsistency(context.Background(), AtPlus)
+	instance := generate()
+	_, id, err := th.Insert(ctx, "webshop", "", instance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, mutationState(ctx))
+
+	var orders []webshop
+	filter := Filter{Where: []Predicate{{Field: "Token", Op: Eq, Value: instance.Token}}}
+	if err := th.Find(ctx, "webshop", filter, &orders); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, orders, 1)
+
+	count, err := th.Count(ctx, "webshop", Filter{Where: []Predicate{{Field: "token", Op: Eq, Value: instance.Token}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), count)
+
+	if err := th.Remove(ctx, "webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Find(ctx, "webshop", filter, &orders); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, orders)
+}
+
+func TestSearchReadYourOwnWrites(t *testing.T) {
+	ctx := WithConsistency(context.Background(), AtPlus)
+	instance := generate()
+	if _, _, err := th.Insert(ctx, "webshop", "", instance, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := th.SimpleSearch(ctx, "webshop_fts_index", &SearchQuery{Query: instance.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, hits)
+}
@@ -0,0 +1,58 @@
+package bucket
+
+import "context"
+
+type contextKey int
+
+const (
+	contextKeyIncludeDeleted contextKey = iota
+	contextKeyActor
+	contextKeyVerifyChildren
+	contextKeyPrepared
+	contextKeyConsistency
+	contextKeyConsistencyState
+)
+
+// IncludeDeleted returns a context which makes the read operations
+// return the trees marked as deleted by the soft delete
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyIncludeDeleted, true)
+}
+
+func includeDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(contextKeyIncludeDeleted).(bool)
+	return v
+}
+
+// WithActor returns a context which carries the actor
+// recorded by the revision history of the writes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, contextKeyActor, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(contextKeyActor).(string)
+	return v
+}
+
+// VerifyChildren returns a context which makes the Exists and ExistsMulti
+// check every child listed in the meta of the root
+func VerifyChildren(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyVerifyChildren, true)
+}
+
+func verifyChildren(ctx context.Context) bool {
+	v, _ := ctx.Value(contextKeyVerifyChildren).(bool)
+	return v
+}
+
+// WithPrepared returns a context which makes the queries prepared or ad-hoc
+// regardless of the PreparedQueries of the configuration
+func WithPrepared(ctx context.Context, prepared bool) context.Context {
+	return context.WithValue(ctx, contextKeyPrepared, prepared)
+}
+
+func preparedFromContext(ctx context.Context) (bool, bool) {
+	v, ok := ctx.Value(contextKeyPrepared).(bool)
+	return v, ok
+}
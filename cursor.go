@@ -0,0 +1,155 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/couchbase/gocb"
+)
+
+// cursor is the position of the last result of a page, the order binds
+// the cursor to the ordering of the query it was returned by, the null
+// and missing sort values of the Find are stored as nil values
+type cursor struct {
+	Key    string        `json:"k"`
+	Values []interface{} `json:"v,omitempty"`
+	Order  string        `json:"o"`
+}
+
+// encodeCursor returns the opaque token of the cursor
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses the token of a cursor returned with the order, the
+// numbers are kept as json.Number so the large integers stay exact
+func decodeCursor(token, order string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil || c.Order != order {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// SearchPage is a page of the PagedSearch's hits with the cursor of
+// the next page, the cursor is empty on the last page
+type SearchPage struct {
+	Hits   []gocb.SearchResultHit
+	Cursor string
+}
+
+type searchPageRequest struct {
+	Query       interface{}    `json:"query"`
+	Size        int            `json:"size"`
+	Sort        []string       `json:"sort"`
+	SearchAfter []interface{}  `json:"search_after,omitempty"`
+	Ctl         *searchPageCtl `json:"ctl,omitempty"`
+}
+
+type searchPageCtl struct {
+	Consistency searchPageConsistency `json:"consistency"`
+}
+
+type searchPageConsistency struct {
+	Level   string              `json:"level"`
+	Vectors *gocb.MutationState `json:"vectors"`
+}
+
+type searchPageHit struct {
+	gocb.SearchResultHit
+	Sort []interface{} `json:"sort"`
+}
+
+type searchPageResponse struct {
+	Hits  []searchPageHit `json:"hits"`
+	Error string          `json:"error"`
+}
+
+// PagedSearch runs a SearchQuery, CompoundQueries or RangeQuery page by page, the hits are
+// ordered by the sort fields (the - prefix means descending) and the document id, the
+// token is the Cursor of the previous page or empty for the first page
+func (h *Handler) PagedSearch(ctx context.Context, index string, q interface{}, sort []string, token string, limit int) (*SearchPage, error) {
+	if index == "" {
+		return nil, ErrEmptyIndex
+	}
+	s, ok := q.(interface{ setup() error })
+	if !ok {
+		return nil, ErrInvalidSearchQuery
+	}
+	if err := s.setup(); err != nil {
+		return nil, err
+	}
+
+	sort = append(append([]string{}, sort...), "_id")
+	order := strings.Join(sort, ",")
+	request := searchPageRequest{
+		Query: q,
+		Size:  limit,
+		Sort:  sort,
+	}
+	if token != "" {
+		c, err := decodeCursor(token, order)
+		if err != nil {
+			return nil, err
+		}
+		request.SearchAfter = c.Values
+	}
+	if state := mutationState(ctx); state != nil && scanConsistency(ctx) != NotBounded {
+		request.Ctl = &searchPageCtl{Consistency: searchPageConsistency{Level: "at_plus", Vectors: state}}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest("POST", h.fullTextSearchURL(ctx, index)+"/query", bytes.NewBuffer(body))
+	setupBasicAuth(req)
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := h.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respbody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var sr searchPageResponse
+	if err := json.Unmarshal(respbody, &sr); err != nil {
+		return nil, fmt.Errorf("search failed with %s: %s", resp.Status, respbody)
+	}
+	if sr.Error != "" {
+		return nil, errors.New(sr.Error)
+	}
+
+	page := &SearchPage{}
+	for _, hit := range sr.Hits {
+		page.Hits = append(page.Hits, hit.SearchResultHit)
+	}
+	if n := len(sr.Hits); limit > 0 && n == limit {
+		last := sr.Hits[n-1]
+		if page.Cursor, err = encodeCursor(cursor{Key: last.Id, Values: last.Sort, Order: order}); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
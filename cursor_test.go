@@ -0,0 +1,52 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	token, err := encodeCursor(cursor{Key: "webshop::1", Values: []interface{}{"processed", int64(9007199254740993)}, Order: "webshop:status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := decodeCursor(token, "webshop:status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "webshop::1", c.Key)
+	assert.Equal(t, []interface{}{"processed", json.Number("9007199254740993")}, c.Values)
+
+	_, err = decodeCursor(token, "webshop:email")
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = decodeCursor("not a cursor", "webshop:status")
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestPagedSearch(t *testing.T) {
+	ctx := context.Background()
+	q := &SearchQuery{Query: "processed"}
+
+	first, err := th.PagedSearch(ctx, "webshop_fts_index", q, nil, "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, first.Hits, 5)
+	assert.NotEmpty(t, first.Cursor)
+
+	second, err := th.PagedSearch(ctx, "webshop_fts_index", q, nil, first.Cursor, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, second.Hits)
+	assert.True(t, first.Hits[4].Id < second.Hits[0].Id)
+
+	_, err = th.PagedSearch(ctx, "webshop_fts_index", q, []string{"-status"}, first.Cursor, 5)
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = th.PagedSearch(ctx, "webshop_fts_index", "processed", nil, "", 5)
+	assert.Equal(t, ErrInvalidSearchQuery, err)
+}
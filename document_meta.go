@@ -1,7 +1,20 @@
 package bucket
 
+import (
+	"time"
+
+	"github.com/couchbase/gocb"
+)
+
 const (
 	metaFieldName = "_meta"
+	metaDeletedAt = metaFieldName + "._deleted_at"
+	metaChildren  = metaFieldName + "._children"
+	metaType      = metaFieldName + "._type"
+	metaParentKey = metaFieldName + "._parent.`key`"
+	metaLookups   = metaFieldName + "._lookups"
+
+	metaCreatedAtPath = metaFieldName + "." + metaCreatedAt
 )
 
 type metaContainer struct {
@@ -12,12 +25,19 @@ type meta struct {
 	ChildDocuments []documentMeta `json:"_children"`
 	ParentDocument *documentMeta  `json:"_parent"`
 	Type           string         `json:"_type"`
+	Lookups        []string       `json:"_lookups,omitempty"`
+	TTL            uint32         `json:"_ttl,omitempty"`
+	Proto          string         `json:"_proto,omitempty"`
+	DeletedAt      *time.Time     `json:"_deleted_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 type documentMeta struct {
 	Key  string `json:"key"`
 	Type string `json:"type"`
 	ID   string `json:"id"`
+	TTL  uint32 `json:"ttl,omitempty"`
 }
 
 func (h *Handler) getMeta(typ, id string) (*meta, error) {
@@ -32,10 +52,71 @@ func (h *Handler) getMeta(typ, id string) (*meta, error) {
 	return c.Meta, nil
 }
 
-func (m *meta) AddChildDocument(key, typ, id string) {
+// storedMetas reads the creation time of the stored documents of the tree and the lookups
+// and deletion time of the root by sub-document lookups without reading the bodies, the
+// binary documents are read whole, the documents don't exist yet are left out
+func (h *Handler) storedMetas(typ, id string, documents map[string]map[string]interface{}) (map[string]*meta, error) {
+	var metas = make(map[string]*meta)
+	for k := range documents {
+		m, err := h.storedMeta(k, id, k == typ)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metas[k] = m
+		}
+	}
+	return metas, nil
+}
+
+// storedMeta reads the creation time of the document, with root the lookups and
+// the deletion time too, nil means missing document
+func (h *Handler) storedMeta(typ, id string, root bool) (*meta, error) {
+	if !h.state.binary(typ) {
+		lookup := h.state.bucket.LookupIn(h.state.getDocumentKey(typ, id)).Get(metaCreatedAtPath)
+		if root {
+			lookup = lookup.Get(metaLookups).Get(metaDeletedAt)
+		}
+		fragment, err := lookup.Execute()
+		switch {
+		case err == gocb.ErrKeyNotFound:
+			return nil, nil
+		case err == nil || err == gocb.ErrSubDocBadMulti:
+			if fragment.ContentByIndex(0, nil) != gocb.ErrSubDocNotJson {
+				var m meta
+				var fields = map[string]interface{}{metaCreatedAtPath: &m.CreatedAt}
+				if root {
+					fields[metaLookups] = &m.Lookups
+					fields[metaDeletedAt] = &m.DeletedAt
+				}
+				for path, v := range fields {
+					if err := fragment.Content(path, v); err != nil && err != gocb.ErrSubDocPathNotFound {
+						return nil, err
+					}
+				}
+				return &m, nil
+			}
+		case err != gocb.ErrSubDocNotJson:
+			return nil, err
+		}
+	}
+
+	m, err := h.getMeta(typ, id)
+	if err == gocb.ErrKeyNotFound {
+		return nil, nil
+	}
+	return m, err
+}
+
+func (m *meta) AddChildDocument(key, typ, id string, ttl uint32) {
 	m.ChildDocuments = append(m.ChildDocuments, documentMeta{
 		Key:  key,
 		Type: typ,
 		ID:   id,
+		TTL:  ttl,
 	})
 }
+
+func (m *meta) AddLookup(key string) {
+	m.Lookups = append(m.Lookups, key)
+}
@@ -1,8 +1,12 @@
 package bucket
 
 import (
+	"context"
 	"strings"
 	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetMeta(t *testing.T) {
@@ -16,6 +20,41 @@ func TestGetMeta(t *testing.T) {
 		t.Fatal(err)
 	}
 	if !strings.Contains(m.ChildDocuments[0].Key, "origin::") {
-		t.Errorf("Referenced first elem should contain 'origin::', instead of %s", m.ChildDocuments[0])
+		t.Errorf("Referenced first elem should contain 'origin::', instead of %s", m.ChildDocuments[0].Key)
+	}
+}
+
+func TestStoredMetas(t *testing.T) {
+	c := generateUniqueCustomer()
+	_, id, err := th.Insert(context.Background(), "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := th.getSubDocuments("unique_customer", id, &c, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := th.storedMetas("unique_customer", id, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := th.getMeta("unique_customer", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := th.getMeta("unique_account", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, root.CreatedAt.Equal(stored["unique_customer"].CreatedAt))
+	assert.Equal(t, root.Lookups, stored["unique_customer"].Lookups)
+	assert.Nil(t, stored["unique_customer"].DeletedAt)
+	assert.True(t, account.CreatedAt.Equal(stored["unique_account"].CreatedAt))
+
+	missing, err := th.storedMetas("unique_customer", xid.New().String(), kv)
+	if err != nil {
+		t.Fatal(err)
 	}
+	assert.Len(t, missing, 0)
 }
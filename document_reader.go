@@ -0,0 +1,127 @@
+package bucket
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const (
+	metaCreatedAt = "created_at"
+	metaUpdatedAt = "updated_at"
+)
+
+// documentReader decodes a document into the value and keeps its meta, so the
+// fields tagged with cb_meta can be filled after the read, the encrypted fields
+// are decrypted before the decode
+type documentReader struct {
+	h         *Handler
+	key       string
+	ptr       interface{}
+	encrypted bool
+	meta      metaContainer
+}
+
+// newDocumentReader wraps the ptr into a documentReader of the document stored by the key
+// if it's necessary, the protobuf messages are wrapped into a protoReader
+func (h *Handler) newDocumentReader(key string, ptr interface{}) interface{} {
+	t := reflect.TypeOf(ptr)
+	if isProtoMessage(ptr) {
+		registerProtoType(protoMessageOf(t))
+		return &protoReader{h: h, key: key, ptr: ptr, encrypted: hasEncryptedFields(t)}
+	}
+	if !hasMetaFields(t) && !hasEncryptedFields(t) {
+		return ptr
+	}
+	return &documentReader{h: h, key: key, ptr: ptr, encrypted: hasEncryptedFields(t)}
+}
+
+func (r *documentReader) UnmarshalJSON(data []byte) error {
+	if r.encrypted {
+		var err error
+		if data, err = r.h.decryptDocument(r.key, data); err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal(data, r.ptr); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.meta)
+}
+
+// rootReader keeps the meta of a root document next to the value decoded by the
+// wrapped reader, so the soft deleted trees can be recognised after a bulk read
+type rootReader struct {
+	value interface{}
+	meta  metaContainer
+}
+
+func (r *rootReader) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, r.value); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.meta)
+}
+
+// deleted reports the root document is marked as deleted by the soft delete
+func (r *rootReader) deleted() bool {
+	return r.meta.Meta != nil && r.meta.Meta.DeletedAt != nil
+}
+
+// finishReaders fills the meta fields of the values read by documentReaders
+func finishReaders(values ...interface{}) {
+	for _, v := range values {
+		if r, ok := v.(*rootReader); ok {
+			v = r.value
+		}
+		if r, ok := v.(*documentReader); ok && r.meta.Meta != nil {
+			setMetaFields(reflect.ValueOf(r.ptr), r.meta.Meta)
+		}
+	}
+}
+
+func hasMetaFields(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup(tagMeta); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func setMetaFields(rv reflect.Value, m *meta) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		var t time.Time
+		switch rt.Field(i).Tag.Get(tagMeta) {
+		case metaCreatedAt:
+			t = m.CreatedAt
+		case metaUpdatedAt:
+			t = m.UpdatedAt
+		default:
+			continue
+		}
+
+		field := rv.Field(i)
+		switch {
+		case field.Type() == reflect.TypeOf(t):
+			field.Set(reflect.ValueOf(t))
+		case field.Type() == reflect.TypeOf(&t):
+			field.Set(reflect.ValueOf(&t))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timestampedNote struct {
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"-" cb_meta:"created_at"`
+	UpdatedAt *time.Time `json:"-" cb_meta:"updated_at"`
+}
+
+func TestDocumentReader(t *testing.T) {
+	var note timestampedNote
+	value := th.newDocumentReader("", &note)
+	if _, ok := value.(*documentReader); !ok {
+		t.Fatalf("value should be documentReader, instead of %T", value)
+	}
+
+	created := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	data, _ := json.Marshal(map[string]interface{}{
+		"text":        "note",
+		metaFieldName: &meta{CreatedAt: created, UpdatedAt: updated},
+	})
+	if err := json.Unmarshal(data, value); err != nil {
+		t.Fatal(err)
+	}
+	finishReaders(value)
+
+	assert.Equal(t, "note", note.Text)
+	assert.Equal(t, created, note.CreatedAt)
+	assert.Equal(t, updated, *note.UpdatedAt)
+}
+
+func TestNewDocumentReaderWithoutMetaFields(t *testing.T) {
+	ws := &webshop{}
+	if value := th.newDocumentReader("", ws); value != ws {
+		t.Errorf("value should be the original pointer, instead of %T", value)
+	}
+	assert.False(t, hasMetaFields(nil))
+	assert.True(t, hasMetaFields(reflect.TypeOf(&timestampedNote{})))
+}
+
+func TestHandler_GetTimestamps(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := th.Insert(ctx, "note", "", timestampedNote{Text: "first"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inserted timestampedNote
+	if err := th.Get(ctx, "note", id, &inserted); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, inserted.CreatedAt.IsZero())
+	assert.NotNil(t, inserted.UpdatedAt)
+
+	time.Sleep(10 * time.Millisecond)
+	if _, _, err := th.Upsert(ctx, "note", id, timestampedNote{Text: "second"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var upserted timestampedNote
+	if err := th.Get(ctx, "note", id, &upserted); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, inserted.CreatedAt, upserted.CreatedAt)
+	assert.True(t, upserted.UpdatedAt.After(*inserted.UpdatedAt))
+}
@@ -0,0 +1,209 @@
+package bucket
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+)
+
+const (
+	tagEncrypted = "cb_encrypted" // encrypted tag represents the fields stored encrypted by the named key
+
+	encryptionAlgorithm = "AES-GCM"
+)
+
+// KeyProvider provides the keys of the field encryption, the keys must be 16, 24 or 32 bytes long
+type KeyProvider interface {
+	// CurrentKey returns the id and the key the new values of the key name are encrypted with
+	CurrentKey(name string) (string, []byte, error)
+
+	// Key returns a current or an earlier key by its id, so the values encrypted before a rotation can be decrypted
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is an in-memory KeyProvider, the last added key of a name is its current key
+type KeyRing struct {
+	mu      sync.RWMutex
+	current map[string]string
+	keys    map[string][]byte
+}
+
+// NewKeyRing creates an empty KeyRing
+func NewKeyRing() *KeyRing {
+	return &KeyRing{
+		current: make(map[string]string),
+		keys:    make(map[string][]byte),
+	}
+}
+
+// Add adds a key to the ring and makes it the current key of the name
+func (r *KeyRing) Add(name, id string, key []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current[name] = id
+	r.keys[id] = key
+}
+
+// CurrentKey returns the last added key of the name
+func (r *KeyRing) CurrentKey(name string) (string, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.current[name]
+	if !ok {
+		return "", nil, ErrEncryptionKeyNotFound
+	}
+	return id, r.keys[id], nil
+}
+
+// Key returns the key by its id
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrEncryptionKeyNotFound
+	}
+	return key, nil
+}
+
+// encryptedValue is stored in place of the encrypted fields, the ciphertext is the nonce
+// followed by the sealed JSON of the value, sealed with the document key and the json
+// name of the field as additional data, so it can't be moved to another field or document
+type encryptedValue struct {
+	KeyID      string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptValue encrypts the JSON of the value of the field of the document with the current key of the key name
+func (h *Handler) encryptValue(name, documentKey, field string, value interface{}) (*encryptedValue, error) {
+	if h.state.configuration.KeyProvider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	id, key, err := h.state.configuration.KeyProvider.CurrentKey(name)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return &encryptedValue{
+		KeyID:      id,
+		Algorithm:  encryptionAlgorithm,
+		Ciphertext: gcm.Seal(nonce, nonce, plaintext, additionalData(documentKey, field)),
+	}, nil
+}
+
+// decryptValue returns the JSON of the encrypted value of the field of the document
+func (h *Handler) decryptValue(documentKey, field string, v encryptedValue) (json.RawMessage, error) {
+	if h.state.configuration.KeyProvider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	key, err := h.state.configuration.KeyProvider.Key(v.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Ciphertext) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := v.Ciphertext[:gcm.NonceSize()], v.Ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData(documentKey, field))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// decryptDocument replaces the encrypted fields of the document stored by the key with their JSON,
+// the fields stored in clear before the encryption was enabled are left as they are
+func (h *Handler) decryptDocument(documentKey string, data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var decrypted bool
+	for k, raw := range fields {
+		if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			continue
+		}
+		var v encryptedValue
+		if err := json.Unmarshal(raw, &v); err != nil || v.Algorithm != encryptionAlgorithm || v.KeyID == "" {
+			continue
+		}
+		plaintext, err := h.decryptValue(documentKey, k, v)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = plaintext
+		decrypted = true
+	}
+	if !decrypted {
+		return data, nil
+	}
+
+	return json.Marshal(fields)
+}
+
+// additionalData binds the ciphertext to the key of the document and the json name of the field
+func additionalData(documentKey, field string) []byte {
+	return []byte(documentKey + "\x00" + field)
+}
+
+// encryptionKeyName returns the key name of the field tagged with cb_encrypted, the
+// encrypted fields can't be unique, because their lookups would store the values in clear
+func encryptionKeyName(field reflect.StructField) (string, bool, error) {
+	name, ok := field.Tag.Lookup(tagEncrypted)
+	if ok && field.Tag.Get(tagUnique) == "true" {
+		return "", false, ErrEncryptedUnique
+	}
+	return name, ok, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func hasEncryptedFields(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup(tagEncrypted); ok {
+			return true
+		}
+	}
+	return false
+}
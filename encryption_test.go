@@ -0,0 +1,208 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/stretchr/testify/assert"
+)
+
+type encryptedCustomer struct {
+	Email   string            `json:"email"`
+	Name    string            `json:"name" cb_encrypted:"webshop_pii"`
+	Phone   string            `json:"phone" cb_encrypted:"webshop_pii"`
+	Address *encryptedAddress `json:"address" cb_referenced:"encrypted_address"`
+}
+
+type encryptedAddress struct {
+	City     string `json:"city"`
+	Address1 string `json:"address_1" cb_encrypted:"webshop_pii"`
+}
+
+type encryptedUniqueCustomer struct {
+	Email string `json:"email" cb_unique:"true" cb_encrypted:"webshop_pii"`
+}
+
+func generateEncryptedCustomer() encryptedCustomer {
+	return encryptedCustomer{
+		Email: gofakeit.Email(),
+		Name:  gofakeit.Name(),
+		Phone: gofakeit.Phone(),
+		Address: &encryptedAddress{
+			City:     gofakeit.City(),
+			Address1: gofakeit.Street(),
+		},
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	encrypted, err := th.encryptValue("webshop_pii", "encrypted_customer::1", "name", "John Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "webshop_pii_1", encrypted.KeyID)
+	assert.Equal(t, encryptionAlgorithm, encrypted.Algorithm)
+	assert.NotContains(t, string(encrypted.Ciphertext), "John Doe")
+
+	plaintext, err := th.decryptValue("encrypted_customer::1", "name", *encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `"John Doe"`, string(plaintext))
+}
+
+func TestEncryptValueKeyRotation(t *testing.T) {
+	keys := NewKeyRing()
+	keys.Add("rotated", "rotated_1", []byte("0123456789abcdef"))
+	h := &Handler{state: &state{configuration: &Configuration{KeyProvider: keys}}}
+
+	previous, err := h.encryptValue("rotated", "rotated::1", "amount", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Add("rotated", "rotated_2", []byte("fedcba9876543210"))
+	current, err := h.encryptValue("rotated", "rotated::1", "amount", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "rotated_2", current.KeyID)
+
+	for _, v := range []*encryptedValue{previous, current} {
+		plaintext, err := h.decryptValue("rotated::1", "amount", *v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "42", string(plaintext))
+	}
+}
+
+func TestDecryptValueInvalidCiphertext(t *testing.T) {
+	encrypted, err := th.encryptValue("webshop_pii", "encrypted_customer::1", "name", "John Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted.Ciphertext[len(encrypted.Ciphertext)-1] ^= 0xFF
+	if _, err := th.decryptValue("encrypted_customer::1", "name", *encrypted); err != ErrInvalidCiphertext {
+		t.Errorf("error should be %s instead of %v", ErrInvalidCiphertext, err)
+	}
+}
+
+func TestDecryptValueMovedCiphertext(t *testing.T) {
+	encrypted, err := th.encryptValue("webshop_pii", "encrypted_customer::1", "name", "John Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.decryptValue("encrypted_customer::1", "phone", *encrypted); err != ErrInvalidCiphertext {
+		t.Errorf("error should be %s instead of %v", ErrInvalidCiphertext, err)
+	}
+	if _, err := th.decryptValue("encrypted_customer::2", "name", *encrypted); err != ErrInvalidCiphertext {
+		t.Errorf("error should be %s instead of %v", ErrInvalidCiphertext, err)
+	}
+}
+
+func TestEncryptValueWithoutKeyProvider(t *testing.T) {
+	h := &Handler{state: &state{configuration: &Configuration{}}}
+	if _, err := h.encryptValue("webshop_pii", "encrypted_customer::1", "name", "John Doe"); err != ErrNoKeyProvider {
+		t.Errorf("error should be %s instead of %v", ErrNoKeyProvider, err)
+	}
+	if _, err := NewKeyRing().Key("missing"); err != ErrEncryptionKeyNotFound {
+		t.Errorf("error should be %s instead of %v", ErrEncryptionKeyNotFound, err)
+	}
+}
+
+func TestDecryptDocument(t *testing.T) {
+	encrypted, err := th.encryptValue("webshop_pii", "encrypted_customer::1", "name", "John Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"name":  encrypted,
+		"email": "john@doe.com",
+	})
+
+	decrypted, err := th.decryptDocument("encrypted_customer::1", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c encryptedCustomer
+	if err := json.Unmarshal(decrypted, &c); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "John Doe", c.Name)
+	assert.Equal(t, "john@doe.com", c.Email)
+}
+
+func TestInsertEncryptedFields(t *testing.T) {
+	ctx := context.Background()
+	c := generateEncryptedCustomer()
+	_, id, err := th.Insert(ctx, "encrypted_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]interface{}
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("encrypted_customer", id), &raw); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, c.Name, raw["name"])
+	assert.NotEqual(t, c.Phone, raw["phone"])
+	assert.Equal(t, c.Email, raw["email"])
+
+	var address map[string]interface{}
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("encrypted_address", id), &address); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, c.Address.Address1, address["address_1"])
+	assert.Equal(t, c.Address.City, address["city"])
+
+	var got encryptedCustomer
+	if err := th.Get(ctx, "encrypted_customer", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c, got)
+}
+
+func TestInsertEncryptedUniqueExpectError(t *testing.T) {
+	c := encryptedUniqueCustomer{Email: gofakeit.Email()}
+	if _, _, err := th.Insert(context.Background(), "encrypted_unique_customer", "", c, 0); err != ErrEncryptedUnique {
+		t.Errorf("error should be %s instead of %v", ErrEncryptedUnique, err)
+	}
+}
+
+func TestGetTamperedEncryptedFieldExpectError(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := th.Insert(ctx, "encrypted_customer", "", generateEncryptedCustomer(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := th.state.getDocumentKey("encrypted_customer", id)
+	var raw map[string]json.RawMessage
+	if _, err := th.state.bucket.Get(key, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ciphertext copied to another document can't be decrypted either
+	copied := th.state.getDocumentKey("encrypted_customer", id+"_copy")
+	if _, err := th.state.bucket.Upsert(copied, raw, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer th.state.bucket.Remove(copied, 0)
+	if err := th.Get(ctx, "encrypted_customer", id+"_copy", &encryptedCustomer{}); err != ErrInvalidCiphertext {
+		t.Errorf("error should be %s instead of %v", ErrInvalidCiphertext, err)
+	}
+
+	var name encryptedValue
+	if err := json.Unmarshal(raw["name"], &name); err != nil {
+		t.Fatal(err)
+	}
+	name.Ciphertext[len(name.Ciphertext)-1] ^= 0xFF
+	raw["name"], _ = json.Marshal(name)
+	if _, err := th.state.bucket.Replace(key, raw, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Get(ctx, "encrypted_customer", id, &encryptedCustomer{}); err != ErrInvalidCiphertext {
+		t.Errorf("error should be %s instead of %v", ErrInvalidCiphertext, err)
+	}
+}
@@ -12,6 +12,9 @@ var (
 	// ErrEmptyField field must be filled
 	ErrEmptyField = errors.New("field must be filled")
 
+	// ErrEmptyID id must be filled
+	ErrEmptyID = errors.New("id must be filled")
+
 	// ErrEmptyIndex index must be filled
 	ErrEmptyIndex = errors.New("index must be filled")
 
@@ -39,6 +42,96 @@ var (
 	// ErrInvalidBulkContainer bulk container type definition error
 	ErrInvalidBulkContainer = errors.New("container must be *[]T, with length of ids array")
 
+	// ErrNotFound document not found or marked as deleted
+	ErrNotFound = errors.New("document not found")
+
+	// ErrUniqueConstraintViolation a unique field value is already used by another document
+	ErrUniqueConstraintViolation = errors.New("unique constraint violation")
+
+	// ErrInvalidMsgpackKey the keys of the MessagePack maps must be strings
+	ErrInvalidMsgpackKey = errors.New("msgpack map key must be string")
+
+	// ErrProtobufCodecModel the protobuf codec stores only protobuf messages without encrypted fields
+	ErrProtobufCodecModel = errors.New("protobuf codec needs protobuf message models without encrypted fields")
+
+	// ErrUnknownProtobufMessage the message type of a protobuf document is neither registered nor used before
+	ErrUnknownProtobufMessage = errors.New("unknown protobuf message type")
+
+	// ErrInvalidProtobufDocument the protobuf document is malformed
+	ErrInvalidProtobufDocument = errors.New("invalid protobuf document")
+
+	// ErrSubdocumentCodec sub-document operations can be used only on documents stored as JSON
+	ErrSubdocumentCodec = errors.New("sub-document operations need JSON documents")
+
+	// ErrNoKeyProvider the encrypted fields need a KeyProvider in the configuration
+	ErrNoKeyProvider = errors.New("key provider must set for encrypted fields")
+
+	// ErrEncryptionKeyNotFound the key provider doesn't have the requested key
+	ErrEncryptionKeyNotFound = errors.New("encryption key not found")
+
+	// ErrInvalidCiphertext the encrypted value can't be decrypted with its key
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+	// ErrEncryptedUnique the encrypted fields can't be tagged with cb_unique
+	ErrEncryptedUnique = errors.New("encrypted fields can't be unique")
+
+	// ErrBlobChecksum the data of the blob doesn't match the checksum of its manifest
+	ErrBlobChecksum = errors.New("blob checksum mismatch")
+
+	// ErrBlobClosed the blob reader is already closed
+	ErrBlobClosed = errors.New("blob reader is closed")
+
+	// ErrInvalidTTL the cb_ttl tag must be a duration or seconds of at least 1 second
+	ErrInvalidTTL = errors.New("invalid ttl")
+
+	// ErrInvalidFindContainer find container type definition error
+	ErrInvalidFindContainer = errors.New("container must be *[]T or *[]*T")
+
+	// ErrUnknownField the field of the filter isn't stored in the root document
+	ErrUnknownField = errors.New("unknown field")
+
+	// ErrReferencedField the referenced document itself can't be queried or put into a view, only its fields by their path
+	ErrReferencedField = errors.New("referenced fields can't be queried")
+
+	// ErrEncryptedField the filter can't use the encrypted fields
+	ErrEncryptedField = errors.New("encrypted fields can't be queried")
+
+	// ErrInvalidPredicate the operator is unknown or the value of IN isn't a slice
+	ErrInvalidPredicate = errors.New("invalid predicate")
+
+	// ErrInvalidCursor the cursor is malformed or belongs to another ordering
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrInvalidSearchQuery the query of the paged search must be a SearchQuery, CompoundQueries or RangeQuery
+	ErrInvalidSearchQuery = errors.New("query must be *SearchQuery, *CompoundQueries or *RangeQuery")
+
+	// ErrScanType the Next of a Scanner must be called with the same type
+	ErrScanType = errors.New("scanner needs the same type at every call")
+
+	// ErrInvalidAggregateContainer aggregate container type definition error
+	ErrInvalidAggregateContainer = errors.New("container must be *[]T")
+
+	// ErrInvalidAggregation the aggregation needs a name, a known function and a field
+	ErrInvalidAggregation = errors.New("invalid aggregation")
+
+	// ErrInvalidView the view needs a name and a map function or a field
+	ErrInvalidView = errors.New("view must have a name and a map or a field")
+
+	// ErrInvalidViewContainer view container type definition error
+	ErrInvalidViewContainer = errors.New("container must be *[]T")
+
+	// ErrInvalidAnalyticsContainer analytics container type definition error
+	ErrInvalidAnalyticsContainer = errors.New("container must be *[]T")
+
+	// ErrInvalidAnalyticsParams the analytics params should be positional or named
+	ErrInvalidAnalyticsParams = errors.New("params must be []interface{} or map[string]interface{}")
+
+	// ErrInvalidDataset the dataset name is the document type, it can't be empty or contain backquote
+	ErrInvalidDataset = errors.New("invalid dataset name")
+
+	// ErrInvalidSearchContainer search container type definition error
+	ErrInvalidSearchContainer = errors.New("container must be *[]T or *[]*T")
+
 	// ErrInvalidGetDocumentTypesParam represents value for get document types should be pointer
 	ErrInvalidGetDocumentTypesParam = errors.New("internal error: value should be pointer for getDocumentTypes")
 )
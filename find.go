@@ -0,0 +1,412 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/gocb"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// findAlias is the alias of the root documents in the statements of the Find
+	findAlias = "d"
+
+	// defaultLoadConcurrency is the number of trees loaded concurrently without LoadConcurrency in the configuration
+	defaultLoadConcurrency = 8
+)
+
+// Operator is the comparison of a Predicate
+type Operator string
+
+// Available operators
+const (
+	Eq   Operator = "="
+	Ne   Operator = "!="
+	Gt   Operator = ">"
+	Gte  Operator = ">="
+	Lt   Operator = "<"
+	Lte  Operator = "<="
+	In   Operator = "IN"
+	Like Operator = "LIKE"
+)
+
+// Predicate is a condition on a field of the root document, the Field is
+// the Go field path (BillingAddress.City) or the json path of the field
+type Predicate struct {
+	Field string
+	Op    Operator
+	Value interface{}
+}
+
+// Order is an ordering of the results by a field
+type Order struct {
+	Field      string
+	Descending bool
+}
+
+// Filter describes the documents returned by the Find, the predicates are joined by AND,
+// the After is the cursor of a previous page and the results continue after it
+type Filter struct {
+	Where   []Predicate
+	OrderBy []Order
+	Limit   int
+	Offset  int
+	After   string
+}
+
+// findRow is a result of the Find's statement, the key of
+// the root document with the values of the ordering
+type findRow struct {
+	Key  string        `json:"key"`
+	Sort []interface{} `json:"sort"`
+}
+
+// Find queries the root documents of the type matching the filter by N1QL and loads
+// their trees into the container, the container should be *[]T or *[]*T type
+func (h *Handler) Find(ctx context.Context, typ string, filter Filter, container interface{}) error {
+	_, err := h.FindPage(ctx, typ, filter, container)
+	return err
+}
+
+// FindPage works like the Find and returns the cursor of the next page for the After
+// of the filter, the cursor is empty on the last page or without Limit
+func (h *Handler) FindPage(ctx context.Context, typ string, filter Filter, container interface{}) (string, error) {
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return "", ErrInvalidFindContainer
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return "", ErrInvalidFindContainer
+	}
+
+	found, next, err := h.findRows(ctx, typ, structType, filter)
+	if err != nil {
+		return "", err
+	}
+	trees, err := h.loadTrees(ctx, typ, structType, found)
+	if err != nil {
+		return "", err
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(trees))
+	for _, tree := range trees {
+		// it could be removed since the query
+		if !tree.IsValid() {
+			continue
+		}
+		if elemType.Kind() == reflect.Ptr {
+			result = reflect.Append(result, tree)
+		} else {
+			result = reflect.Append(result, tree.Elem())
+		}
+	}
+	slice.Set(result)
+
+	return next, nil
+}
+
+// findRows runs the statement of the filter and returns the found
+// rows with the cursor of the next page if the page is full
+func (h *Handler) findRows(ctx context.Context, typ string, rt reflect.Type, filter Filter) ([]findRow, string, error) {
+	statement, params, order, err := h.findStatement(ctx, typ, rt, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := h.query(ctx, statement, params)
+	if err != nil {
+		return nil, "", err
+	}
+	var found []findRow
+	var row findRow
+	for rows.Next(&row) {
+		found = append(found, row)
+		row = findRow{}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if n := len(found); filter.Limit > 0 && n == filter.Limit {
+		if next, err = encodeCursor(cursor{Key: found[n-1].Key, Values: found[n-1].Sort, Order: order}); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return found, next, nil
+}
+
+// loadTrees loads the trees of the rows into new values of rt with bounded concurrency, the
+// trees keep the order of the rows and the ones removed since the query are invalid values
+func (h *Handler) loadTrees(ctx context.Context, typ string, rt reflect.Type, rows []findRow) ([]reflect.Value, error) {
+	var (
+		trees    = make([]reflect.Value, len(rows))
+		sem      = make(chan struct{}, h.loadConcurrency())
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			wg.Wait()
+			return nil, err
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			tree := reflect.New(rt)
+			err := h.Get(ctx, typ, h.state.fetchDocumentIdentifier(key), tree.Interface())
+			if err == gocb.ErrKeyNotFound || err == ErrNotFound {
+				return
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			trees[i] = tree
+		}(i, row.Key)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return trees, nil
+}
+
+func (h *Handler) loadConcurrency() int {
+	if h.state.configuration.LoadConcurrency > 0 {
+		return h.state.configuration.LoadConcurrency
+	}
+	return defaultLoadConcurrency
+}
+
+// findStatement builds the parameterised N1QL statement of the Find returning the keys
+// of the root documents with their sort values and the order binding the cursors,
+// the fields are resolved on rt, the type of the root
+func (h *Handler) findStatement(ctx context.Context, typ string, rt reflect.Type, filter Filter) (string, []interface{}, string, error) {
+	joins := &findJoins{}
+	conditions, params, err := h.findConditions(ctx, typ, rt, filter.Where, joins)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	var paths, sorts, orders []string
+	for _, o := range filter.OrderBy {
+		path, err := fieldPath(rt, o.Field, joins)
+		if err != nil {
+			return "", nil, "", err
+		}
+		paths = append(paths, path)
+		// the missing values are ordered as null, so they are continued together by the cursors
+		path = fmt.Sprintf("IFMISSING(%s, NULL)", path)
+		sorts = append(sorts, path)
+		if o.Descending {
+			path += " DESC"
+		}
+		orders = append(orders, path)
+	}
+	// the key makes the order of the equal values stable between the pages
+	key := fmt.Sprintf("META(%s).id", findAlias)
+	orders = append(orders, key)
+	order := typ + ":" + strings.Join(orders, ",")
+
+	if filter.After != "" {
+		c, err := decodeCursor(filter.After, order)
+		if err != nil || len(c.Values) != len(paths) {
+			return "", nil, "", ErrInvalidCursor
+		}
+		var condition string
+		condition, params = keysetCondition(paths, filter.OrderBy, key, c, params)
+		conditions = append(conditions, condition)
+	}
+
+	sort := "[" + strings.Join(sorts, ", ") + "]"
+	statement := fmt.Sprintf("SELECT %s AS `key`, %s AS `sort` FROM %s WHERE %s ORDER BY %s",
+		key, sort, h.findFrom(typ, joins), strings.Join(conditions, " AND "), strings.Join(orders, ", "))
+	// the paging is parameterised so the prepared statement is the same for every page
+	if filter.Limit > 0 {
+		params = append(params, filter.Limit)
+		statement += fmt.Sprintf(" LIMIT $%d", len(params))
+	}
+	if filter.Offset > 0 {
+		params = append(params, filter.Offset)
+		statement += fmt.Sprintf(" OFFSET $%d", len(params))
+	}
+
+	return statement, params, order, nil
+}
+
+// findConditions returns the conditions of the root documents of the type matching
+// the predicates with their parameters, the referenced fields are added to the joins
+func (h *Handler) findConditions(ctx context.Context, typ string, rt reflect.Type, where []Predicate, joins *findJoins) ([]string, []interface{}, error) {
+	params := []interface{}{h.state.getType(typ) + "%", typ}
+	conditions := []string{
+		fmt.Sprintf("META(%s).id LIKE $1", findAlias),
+		fmt.Sprintf("%s.%s = $2", findAlias, metaType),
+	}
+	if !includeDeleted(ctx) {
+		conditions = append(conditions, fmt.Sprintf("%s.%s IS NOT VALUED", findAlias, metaDeletedAt))
+	}
+	for _, p := range where {
+		path, err := fieldPath(rt, p.Field, joins)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch p.Op {
+		case Eq, Ne, Gt, Gte, Lt, Lte, Like:
+		case In:
+			if v := reflect.ValueOf(p.Value); v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return nil, nil, ErrInvalidPredicate
+			}
+		default:
+			return nil, nil, ErrInvalidPredicate
+		}
+		params = append(params, p.Value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", path, p.Op, len(params)))
+	}
+
+	return conditions, params, nil
+}
+
+// keysetCondition returns the condition of the rows after the cursor with the params extended by the
+// values of the cursor and the key of its document, the null and missing values are ordered before
+// the others so they can't be compared, they are tested by IS VALUED and IS NOT VALUED instead
+func keysetCondition(paths []string, orders []Order, key string, c cursor, params []interface{}) (string, []interface{}) {
+	var equals, alternatives []string
+	for i, path := range paths {
+		var equal, after string
+		switch {
+		case c.Values[i] == nil && orders[i].Descending:
+			// nothing is after the null values in descending order
+			equal = path + " IS NOT VALUED"
+		case c.Values[i] == nil:
+			equal, after = path+" IS NOT VALUED", path+" IS VALUED"
+		case orders[i].Descending:
+			params = append(params, c.Values[i])
+			equal = fmt.Sprintf("%s = $%d", path, len(params))
+			after = fmt.Sprintf("(%s < $%d OR %s IS NOT VALUED)", path, len(params), path)
+		default:
+			params = append(params, c.Values[i])
+			equal = fmt.Sprintf("%s = $%d", path, len(params))
+			after = fmt.Sprintf("%s > $%d", path, len(params))
+		}
+		if after != "" {
+			terms := append(append([]string{}, equals...), after)
+			alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		}
+		equals = append(equals, equal)
+	}
+	params = append(params, c.Key)
+	terms := append(equals, fmt.Sprintf("%s > $%d", key, len(params)))
+	alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", params
+}
+
+// fieldPath resolves the Go field path or json path of a field to its N1QL path, the fields of
+// the referenced documents are resolved in their joined documents, the encrypted fields and the
+// referenced documents themselves can't be queried, without rt the path must be the json path
+func fieldPath(rt reflect.Type, path string, joins *findJoins) (string, error) {
+	var (
+		alias  = findAlias
+		names  = strings.Split(path, ".")
+		parts  []string
+		goPath []string
+		root   = true
+	)
+	for i, name := range names {
+		if rt == nil {
+			if name == "" || strings.Contains(name, "`") {
+				return "", ErrUnknownField
+			}
+			parts = append(parts, "`"+name+"`")
+			continue
+		}
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt.Kind() != reflect.Struct {
+			return "", ErrUnknownField
+		}
+		field, jsonName, ok := documentField(rt, name, root)
+		if !ok {
+			return "", ErrUnknownField
+		}
+		goPath = append(goPath, field.Name)
+		if tag, ok := referencedTag(rt, field); ok {
+			if joins == nil || i == len(names)-1 {
+				return "", ErrReferencedField
+			}
+			alias = joins.join(alias, strings.Join(goPath, "."), tag)
+			parts = nil
+			root = true
+			rt = field.Type
+			continue
+		}
+		if _, ok := field.Tag.Lookup(tagEncrypted); ok {
+			return "", ErrEncryptedField
+		}
+		parts = append(parts, "`"+jsonName+"`")
+		rt = field.Type
+		root = false
+	}
+
+	return alias + "." + strings.Join(parts, "."), nil
+}
+
+// documentField looks up a field by its Go or json name, the fields of the documents
+// without json tag aren't stored, the protobuf messages are stored by proto names
+func documentField(rt reflect.Type, name string, root bool) (reflect.StructField, string, bool) {
+	_, isProto := reflect.New(rt).Interface().(proto.Message)
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, ok := field.Tag.Lookup(tagMeta); ok {
+			continue
+		}
+
+		var jsonName string
+		if isProto {
+			jsonName = protoName(field)
+		} else {
+			tag, ok := field.Tag.Lookup(tagJSON)
+			if !ok && root {
+				continue
+			}
+			jsonName = strings.Split(tag, ",")[0]
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+		}
+		if jsonName == "" || jsonName == "-" {
+			continue
+		}
+
+		if field.Name == name || jsonName == name {
+			return field, jsonName, true
+		}
+	}
+
+	return reflect.StructField{}, "", false
+}
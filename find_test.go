@@ -0,0 +1,209 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	var orders []webshop
+	filter := Filter{
+		Where: []Predicate{
+			{Field: "Status", Op: Eq, Value: "processed"},
+			{Field: "final_grand_total", Op: Gte, Value: 100},
+			{Field: "PaymentMethod", Op: In, Value: []string{"card", "cash"}},
+		},
+		OrderBy: []Order{{Field: "CreationDate", Descending: true}},
+		Limit:   5,
+	}
+	if err := th.Find(context.Background(), "webshop", filter, &orders); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEmpty(t, orders)
+	assert.True(t, len(orders) <= 5)
+	for i, order := range orders {
+		assert.Equal(t, "processed", order.Status)
+		assert.NotNil(t, order.Product)
+		assert.NotNil(t, order.Product.Origin)
+		assert.NotEmpty(t, order.CardHolderName)
+		if i > 0 {
+			assert.True(t, orders[i-1].CreationDate >= order.CreationDate)
+		}
+	}
+}
+
+func TestFindPointers(t *testing.T) {
+	var orders []*webshop
+	filter := Filter{
+		Where: []Predicate{{Field: "Email", Op: Like, Value: "%@%"}},
+		Limit: 3,
+	}
+	if err := th.Find(context.Background(), "webshop", filter, &orders); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEmpty(t, orders)
+	for _, order := range orders {
+		assert.NotNil(t, order.Store)
+	}
+}
+
+func TestFindInvalidFilter(t *testing.T) {
+	ctx := context.Background()
+	var orders []webshop
+	assert.Equal(t, ErrInvalidFindContainer, th.Find(ctx, "webshop", Filter{}, orders))
+	assert.Equal(t, ErrUnknownField, th.Find(ctx, "webshop", Filter{Where: []Predicate{{Field: "Unknown", Op: Eq, Value: 1}}}, &orders))
+	assert.Equal(t, ErrReferencedField, th.Find(ctx, "webshop", Filter{Where: []Predicate{{Field: "Product", Op: Eq, Value: "x"}}}, &orders))
+	var customers []encryptedCustomer
+	assert.Equal(t, ErrEncryptedField, th.Find(ctx, "encrypted_customer", Filter{OrderBy: []Order{{Field: "Name"}}}, &customers))
+	assert.Equal(t, ErrInvalidPredicate, th.Find(ctx, "webshop", Filter{Where: []Predicate{{Field: "Status", Op: In, Value: "processed"}}}, &orders))
+	assert.Equal(t, ErrInvalidPredicate, th.Find(ctx, "webshop", Filter{Where: []Predicate{{Field: "Status", Op: "~", Value: "processed"}}}, &orders))
+}
+
+func TestFindStatement(t *testing.T) {
+	filter := Filter{
+		Where:   []Predicate{{Field: "Status", Op: Eq, Value: "processed"}},
+		OrderBy: []Order{{Field: "invoice_number"}},
+		Limit:   10,
+		Offset:  20,
+	}
+	rt := reflect.TypeOf(webshop{})
+	statement, params, _, err := th.findStatement(context.Background(), "webshop", rt, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT META(d).id AS `key`, [IFMISSING(d.`invoice_number`, NULL)] AS `sort` FROM `" + th.state.configuration.BucketName + "` AS d " +
+		"WHERE META(d).id LIKE $1 AND d._meta._type = $2 AND d._meta._deleted_at IS NOT VALUED AND d.`status` = $3 " +
+		"ORDER BY IFMISSING(d.`invoice_number`, NULL), META(d).id LIMIT $4 OFFSET $5"
+	assert.Equal(t, expected, statement)
+	assert.Equal(t, []interface{}{th.state.getType("webshop") + "%", "webshop", "processed", 10, 20}, params)
+
+	statement, _, _, err = th.findStatement(IncludeDeleted(context.Background()), "webshop", rt, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, statement, metaDeletedAt)
+}
+
+func TestFindStatementAfter(t *testing.T) {
+	rt := reflect.TypeOf(webshop{})
+	filter := Filter{
+		OrderBy: []Order{{Field: "Status"}, {Field: "FinalGrandTotal", Descending: true}},
+		Limit:   10,
+	}
+	_, _, order, err := th.findStatement(context.Background(), "webshop", rt, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.After, err = encodeCursor(cursor{Key: "webshop::1", Values: []interface{}{"processed", 443}, Order: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statement, params, _, err := th.findStatement(context.Background(), "webshop", rt, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, statement, "((d.`status` > $3) OR "+
+		"(d.`status` = $3 AND (d.`final_grand_total` < $4 OR d.`final_grand_total` IS NOT VALUED)) OR "+
+		"(d.`status` = $3 AND d.`final_grand_total` = $4 AND META(d).id > $5))")
+	assert.Equal(t, "webshop::1", params[4])
+
+	filter.After, err = encodeCursor(cursor{Key: "webshop::2", Values: []interface{}{nil, nil}, Order: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement, params, _, err = th.findStatement(context.Background(), "webshop", rt, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, statement, "((d.`status` IS VALUED) OR "+
+		"(d.`status` IS NOT VALUED AND d.`final_grand_total` IS NOT VALUED AND META(d).id > $3))")
+	assert.Equal(t, "webshop::2", params[2])
+
+	filter.OrderBy = filter.OrderBy[:1]
+	_, _, _, err = th.findStatement(context.Background(), "webshop", rt, filter)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestFindPage(t *testing.T) {
+	ctx := context.Background()
+	filter := Filter{
+		Where:   []Predicate{{Field: "Status", Op: Eq, Value: "processed"}},
+		OrderBy: []Order{{Field: "CreationDate", Descending: true}},
+		Limit:   5,
+	}
+
+	var first []webshop
+	next, err := th.FindPage(ctx, "webshop", filter, &first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, first, 5)
+	assert.NotEmpty(t, next)
+
+	var second []webshop
+	filter.After = next
+	if _, err := th.FindPage(ctx, "webshop", filter, &second); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, second)
+	for _, a := range first {
+		for _, b := range second {
+			assert.NotEqual(t, a.Token, b.Token)
+		}
+	}
+	assert.True(t, first[len(first)-1].CreationDate >= second[0].CreationDate)
+}
+
+func TestFindStatementJoin(t *testing.T) {
+	filter := Filter{
+		Where: []Predicate{
+			{Field: "Product.Origin.Country", Op: Eq, Value: "Hungary"},
+			{Field: "Product.status", Op: Eq, Value: "active"},
+		},
+		OrderBy: []Order{{Field: "Store.Name"}},
+	}
+	statement, _, _, err := th.findStatement(context.Background(), "webshop", reflect.TypeOf(webshop{}), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset := len(th.state.getType("webshop"))
+	bucket := th.state.configuration.BucketName
+	expected := "SELECT META(d).id AS `key`, [IFMISSING(c3.`name`, NULL)] AS `sort` FROM `" + bucket + "` AS d" +
+		fmt.Sprintf(" LEFT JOIN `%s` AS c1 ON META(c1).id = \"%s\" || SUBSTR(META(d).id, %d) AND c1._meta._parent.`key` = META(d).id", bucket, th.state.getType("product"), offset) +
+		fmt.Sprintf(" LEFT JOIN `%s` AS c2 ON META(c2).id = \"%s\" || SUBSTR(META(d).id, %d) AND c2._meta._parent.`key` = META(c1).id", bucket, th.state.getType("origin"), offset) +
+		fmt.Sprintf(" LEFT JOIN `%s` AS c3 ON META(c3).id = \"%s\" || SUBSTR(META(d).id, %d) AND c3._meta._parent.`key` = META(d).id", bucket, th.state.getType("store"), offset) +
+		" WHERE META(d).id LIKE $1 AND d._meta._type = $2 AND d._meta._deleted_at IS NOT VALUED AND c2.`country` = $3 AND c1.`status` = $4" +
+		" ORDER BY IFMISSING(c3.`name`, NULL), META(d).id"
+	assert.Equal(t, expected, statement)
+}
+
+func TestFindByReferencedField(t *testing.T) {
+	ctx := context.Background()
+	var sample []webshop
+	if err := th.Find(ctx, "webshop", Filter{Limit: 1}, &sample); err != nil || len(sample) == 0 {
+		t.Fatal(err)
+	}
+	country := sample[0].Product.Origin.Country
+
+	var orders []webshop
+	filter := Filter{
+		Where: []Predicate{{Field: "Product.Origin.Country", Op: Eq, Value: country}},
+		Limit: 10,
+	}
+	if err := th.Find(ctx, "webshop", filter, &orders); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, orders)
+	for _, order := range orders {
+		assert.Equal(t, country, order.Product.Origin.Country)
+	}
+}
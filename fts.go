@@ -195,6 +195,7 @@ func (h *Handler) RangeSearchWithFacets(ctx context.Context, index string, q *Ra
 }
 
 func (h *Handler) doSearch(ctx context.Context, query *gocb.SearchQuery) (gocb.SearchResultStatus, []gocb.SearchResultHit, map[string]gocb.SearchResultFacet, error) {
+	consistentSearch(ctx, query)
 	res, err := h.state.bucket.ExecuteSearchQuery(query)
 	if err != nil {
 		if res != nil {
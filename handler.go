@@ -31,7 +31,47 @@ type Configuration struct {
 	ConnectionString string `json:"connection_string"`
 	Separator        string `json:"separator"`
 
-	Opts Opts `json:"bucket_opts"`
+	// Codec is the format of the documents, JSON by default
+	Codec Codec `json:"codec"`
+
+	// CompressThreshold is the size in bytes above the documents are sent compressed by snappy, the
+	// server keeps them as JSON, 0 keeps the default of gocb (32 bytes) and negative disables it
+	CompressThreshold int `json:"compress_threshold"`
+
+	// KeyProvider provides the keys of the fields tagged with cb_encrypted
+	KeyProvider KeyProvider `json:"-"`
+
+	// TouchPolicy defines what the Touch and GetAndTouch do with the children having own ttl, TouchPreserve by default
+	TouchPolicy TouchPolicy `json:"touch_policy"`
+
+	// BlobChunkSize is the size of the chunk documents of the blobs in bytes, 1 MB by default
+	BlobChunkSize int `json:"blob_chunk_size"`
+
+	// PreparedQueries makes the queries prepared and cached by their statement instead of ad-hoc,
+	// the WithPrepared context overrides it per call
+	PreparedQueries bool `json:"prepared_queries"`
+
+	// ScanBatchSize is the number of root documents queried at once by the Scan, 100 by default
+	ScanBatchSize int `json:"scan_batch_size"`
+
+	// LoadConcurrency is the number of trees loaded or checked concurrently by the Find, Scan and ExistsMulti, 8 by default
+	LoadConcurrency int `json:"load_concurrency"`
+
+	Opts  Opts                   `json:"bucket_opts"`
+	Types map[string]TypeOptions `json:"types"`
+}
+
+// TypeOptions is the document type related configuration
+type TypeOptions struct {
+	// SoftDelete makes the Remove mark the tree as deleted instead of removing the documents,
+	// it needs JSON documents because the mark is set by sub-document operation
+	SoftDelete bool `json:"soft_delete"`
+
+	// History makes the Upsert and Replace store the previous version of the changed documents
+	History bool `json:"history"`
+
+	// Codec is the format of the documents of the type, it overrides the Configuration's Codec
+	Codec Codec `json:"codec"`
 }
 
 // Opts is the couchbase related configuration such as timeouts
@@ -82,7 +122,9 @@ func (h *Handler) GetManager(ctx context.Context) *gocb.BucketManager {
 
 // ValidateState validates the state of the bucket
 func (h *Handler) ValidateState() (bool, error) {
-	return h.state.validate()
+	return h.state.validate(func(statement string) (gocb.QueryResults, error) {
+		return h.query(context.Background(), statement, nil)
+	})
 }
 
 func (h *Handler) prepare() {
@@ -90,6 +132,7 @@ func (h *Handler) prepare() {
 }
 
 func (h *Handler) prepareBucket() {
+	h.state.bucket.SetTranscoder(transcoder{})
 	if h.state.configuration.Opts.OperationTimeout.valid {
 		h.state.bucket.SetOperationTimeout(h.state.configuration.Opts.OperationTimeout.Value)
 	}
@@ -0,0 +1,225 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/couchbase/gocb"
+)
+
+const (
+	revisionKeyword = "rev"
+)
+
+// Revision is a previous version of a document stored by the
+// Upsert and Replace when the History enabled for the type
+type Revision struct {
+	Number    uint64          `json:"number"`
+	Key       string          `json:"key"`
+	Timestamp time.Time       `json:"timestamp"`
+	Actor     string          `json:"actor"`
+	Document  json.RawMessage `json:"document"`
+}
+
+// History returns the stored revisions of a document in ascending order,
+// the referenced documents have their own history under their own type
+func (h *Handler) History(ctx context.Context, typ, id string) ([]Revision, error) {
+	var last uint64
+	if _, err := h.state.bucket.Get(h.revisionCounterKey(typ, id), &last); err != nil {
+		if err == gocb.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ops []gocb.BulkOp
+	for n := uint64(1); n <= last; n++ {
+		ops = append(ops, &gocb.GetOp{Key: h.revisionKey(typ, id, n), Value: &Revision{}})
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	for _, op := range ops {
+		getOp := op.(*gocb.GetOp)
+		if getOp.Err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if getOp.Err != nil {
+			return nil, getOp.Err
+		}
+		revisions = append(revisions, *getOp.Value.(*Revision))
+	}
+
+	return revisions, nil
+}
+
+// GetRevision retrieves a revision of a document into the ptr,
+// only the fields of the document itself are filled, the referenced ones aren't
+func (h *Handler) GetRevision(ctx context.Context, typ, id string, rev uint64, ptr interface{}) error {
+	if err := h.inputcheck(ptr); err != nil {
+		return err
+	}
+
+	var revision Revision
+	if _, err := h.state.bucket.Get(h.revisionKey(typ, id, rev), &revision); err != nil {
+		return err
+	}
+
+	value := h.newDocumentReader(h.state.getDocumentKey(typ, id), ptr)
+	if err := json.Unmarshal(revision.Document, value); err != nil {
+		return err
+	}
+	finishReaders(value)
+
+	return nil
+}
+
+func (h *Handler) revisionCounterKey(typ, id string) string {
+	return h.state.getDocumentKey(typ, id) + h.state.configuration.Separator + revisionKeyword
+}
+
+func (h *Handler) revisionKey(typ, id string, rev uint64) string {
+	return fmt.Sprintf("%s%s%d", h.revisionCounterKey(typ, id), h.state.configuration.Separator, rev)
+}
+
+// revisionKeys returns the keys of the revisions and the revision counters
+// of the root and the children listed in the meta, read by their counters
+func (h *Handler) revisionKeys(typ, id string, m *meta) ([]string, error) {
+	var ops []gocb.BulkOp
+	var typs = []string{typ}
+	for _, child := range m.ChildDocuments {
+		typs = append(typs, child.Type)
+	}
+	for _, t := range typs {
+		var last uint64
+		ops = append(ops, &gocb.GetOp{Key: h.revisionCounterKey(t, id), Value: &last})
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for i, op := range ops {
+		getOp := op.(*gocb.GetOp)
+		if getOp.Err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if getOp.Err != nil {
+			return nil, getOp.Err
+		}
+		keys = append(keys, getOp.Key)
+		for n := uint64(1); n <= *getOp.Value.(*uint64); n++ {
+			keys = append(keys, h.revisionKey(typs[i], id, n))
+		}
+	}
+
+	return keys, nil
+}
+
+// removeRevisions removes the revisions of the tree with their counters
+func (h *Handler) removeRevisions(ctx context.Context, typ, id string, m *meta) error {
+	keys, err := h.revisionKeys(typ, id, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := h.remove(ctx, key); err != nil && err != gocb.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentDocuments reads the stored version of the documents of the tree,
+// the documents don't exist yet are left out
+func (h *Handler) currentDocuments(id string, documents map[string]map[string]interface{}) (map[string]json.RawMessage, error) {
+	var ops []gocb.BulkOp
+	var typs []string
+	for k := range documents {
+		ops = append(ops, &gocb.GetOp{Key: h.state.getDocumentKey(k, id), Value: &json.RawMessage{}})
+		typs = append(typs, k)
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return nil, err
+	}
+
+	var current = make(map[string]json.RawMessage)
+	for i, op := range ops {
+		getOp := op.(*gocb.GetOp)
+		if getOp.Err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if getOp.Err != nil {
+			return nil, getOp.Err
+		}
+		current[typs[i]] = *getOp.Value.(*json.RawMessage)
+	}
+
+	return current, nil
+}
+
+// storeRevisions stores the previous version of the documents changed by the write,
+// the revisions and their counters expire with the tree by the ttl of the write
+func (h *Handler) storeRevisions(ctx context.Context, id string, previous map[string]json.RawMessage, documents map[string]map[string]interface{}, ttl uint32) error {
+	for k, raw := range previous {
+		changed, err := h.documentChanged(h.state.getDocumentKey(k, id), raw, documents[k])
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+
+		n, _, token, err := h.state.bucket.CounterMt(h.revisionCounterKey(k, id), 1, 1, ttl)
+		if err != nil {
+			return err
+		}
+		recordMutation(ctx, token)
+		revision := Revision{
+			Number:    n,
+			Key:       h.state.getDocumentKey(k, id),
+			Timestamp: time.Now().UTC(),
+			Actor:     actorFromContext(ctx),
+			Document:  raw,
+		}
+		_, token, err = h.state.bucket.InsertMt(h.revisionKey(k, id, n), revision, ttl)
+		if err != nil {
+			return err
+		}
+		recordMutation(ctx, token)
+	}
+
+	return nil
+}
+
+// documentChanged compares the stored and the new version of the document of the key without their
+// meta, the encrypted fields are compared by their decrypted value because of the random nonces
+func (h *Handler) documentChanged(key string, previous json.RawMessage, current map[string]interface{}) (bool, error) {
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	if previous, err = h.decryptDocument(key, previous); err != nil {
+		return false, err
+	}
+	if encoded, err = h.decryptDocument(key, encoded); err != nil {
+		return false, err
+	}
+
+	var p, c map[string]interface{}
+	if err := json.Unmarshal(previous, &p); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(encoded, &c); err != nil {
+		return false, err
+	}
+	delete(p, metaFieldName)
+	delete(c, metaFieldName)
+
+	return !reflect.DeepEqual(p, c), nil
+}
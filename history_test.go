@@ -0,0 +1,137 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/couchbase/gocb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := WithActor(context.Background(), "tester")
+	ws := generate()
+	_, id, err := th.Insert(ctx, "audit_webshop", "", ws, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := ws
+	updated.Status = "shipped"
+	if _, _, err := th.Upsert(ctx, "audit_webshop", id, updated, 0); err != nil {
+		t.Fatal(err)
+	}
+	// unchanged write doesn't create revision
+	if _, _, err := th.Upsert(ctx, "audit_webshop", id, updated, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := th.History(ctx, "audit_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("Length of revisions should be 1, instead of %d", len(revisions))
+	}
+	assert.Equal(t, uint64(1), revisions[0].Number)
+	assert.Equal(t, "tester", revisions[0].Actor)
+	assert.Equal(t, th.state.getDocumentKey("audit_webshop", id), revisions[0].Key)
+
+	productRevisions, err := th.History(ctx, "product", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, productRevisions, 0)
+
+	var got webshop
+	if err := th.GetRevision(ctx, "audit_webshop", id, 1, &got); err != nil {
+		t.Fatal(err)
+	}
+	ws.Product = nil
+	ws.Store = nil
+	assert.Equal(t, ws, got)
+}
+
+func TestHistoryReplace(t *testing.T) {
+	ctx := context.Background()
+	ws := generate()
+	_, id, err := th.Insert(ctx, "audit_webshop", "", ws, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := generate()
+	if _, err := th.Replace(ctx, "audit_webshop", id, updated, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := th.History(ctx, "product", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("Length of revisions should be 1, instead of %d", len(revisions))
+	}
+
+	var got product
+	if err := th.GetRevision(ctx, "product", id, 1, &got); err != nil {
+		t.Fatal(err)
+	}
+	ws.Product.Origin = nil
+	assert.Equal(t, *ws.Product, got)
+}
+
+func TestHistoryRemove(t *testing.T) {
+	ctx := context.Background()
+	ws := generate()
+	_, id, err := th.Insert(ctx, "audit_webshop", "", ws, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := ws
+	updated.Status = "shipped"
+	updated.Product.Status = "sold"
+	if _, _, err := th.Upsert(ctx, "audit_webshop", id, updated, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := th.Remove(ctx, "audit_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typ := range []string{"audit_webshop", "product"} {
+		revisions, err := th.History(ctx, typ, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, revisions, 0)
+		if _, err := th.state.bucket.Get(th.revisionKey(typ, id, 1), &Revision{}); err != gocb.ErrKeyNotFound {
+			t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+		}
+	}
+}
+
+func TestHistoryWithoutRevisions(t *testing.T) {
+	revisions, err := th.History(context.Background(), "audit_webshop", "nonexistent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, revisions, 0)
+}
+
+func TestDocumentChanged(t *testing.T) {
+	previous := json.RawMessage(`{"status":"processed","_meta":{"_type":"webshop"}}`)
+
+	changed, err := th.documentChanged("webshop::1", previous, map[string]interface{}{"status": "processed", metaFieldName: &meta{Type: "other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, changed)
+
+	changed, err = th.documentChanged("webshop::1", previous, map[string]interface{}{"status": "shipped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, changed)
+}
@@ -0,0 +1,153 @@
+package bucket
+
+import (
+	"context"
+	"reflect"
+)
+
+// BeforeInserter is implemented by the models which have to run before the Insert
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// BeforeUpserter is implemented by the models which have to run before the Upsert
+type BeforeUpserter interface {
+	BeforeUpsert(ctx context.Context) error
+}
+
+// BeforeReplacer is implemented by the models which have to run before the Replace
+type BeforeReplacer interface {
+	BeforeReplace(ctx context.Context) error
+}
+
+// AfterGetter is implemented by the models which have to run after the Get, GetAndTouch and GetBulk
+type AfterGetter interface {
+	AfterGet(ctx context.Context) error
+}
+
+// BeforeRemover is implemented by the models which have to run before the Remove
+type BeforeRemover interface {
+	BeforeRemove(ctx context.Context) error
+}
+
+var beforeRemoverType = reflect.TypeOf((*BeforeRemover)(nil)).Elem()
+
+func beforeInsert(ctx context.Context, v interface{}) error {
+	return callHooks(v, func(model interface{}) error {
+		if m, ok := model.(BeforeInserter); ok {
+			return m.BeforeInsert(ctx)
+		}
+		return nil
+	})
+}
+
+func beforeUpsert(ctx context.Context, v interface{}) error {
+	return callHooks(v, func(model interface{}) error {
+		if m, ok := model.(BeforeUpserter); ok {
+			return m.BeforeUpsert(ctx)
+		}
+		return nil
+	})
+}
+
+func beforeReplace(ctx context.Context, v interface{}) error {
+	return callHooks(v, func(model interface{}) error {
+		if m, ok := model.(BeforeReplacer); ok {
+			return m.BeforeReplace(ctx)
+		}
+		return nil
+	})
+}
+
+func afterGet(ctx context.Context, v interface{}) error {
+	return callHooks(v, func(model interface{}) error {
+		if m, ok := model.(AfterGetter); ok {
+			return m.AfterGet(ctx)
+		}
+		return nil
+	})
+}
+
+func beforeRemove(ctx context.Context, v interface{}) error {
+	return callHooks(v, func(model interface{}) error {
+		if m, ok := model.(BeforeRemover); ok {
+			return m.BeforeRemove(ctx)
+		}
+		return nil
+	})
+}
+
+// callHooks calls the hook on the root and every referenced child of the tree
+func callHooks(v interface{}, hook func(interface{}) error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	if err := hook(v); err != nil {
+		return err
+	}
+
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		if _, ok := referencedTag(rv.Type(), rv.Type().Field(i)); !ok {
+			continue
+		}
+
+		field := rv.Field(i)
+		if field.Kind() == reflect.Struct && field.CanAddr() {
+			field = field.Addr()
+		}
+		if field.Kind() != reflect.Ptr || field.IsNil() || !field.CanInterface() {
+			continue
+		}
+		if err := callHooks(field.Interface(), hook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasHook checks the root or a referenced child type of the tree implements the hook
+// by its pointer, so the hooks with pointer receiver are found too
+func hasHook(rt reflect.Type, hook reflect.Type) bool {
+	return hasHookVisited(rt, hook, make(map[reflect.Type]bool))
+}
+
+func hasHookVisited(rt reflect.Type, hook reflect.Type, visited map[reflect.Type]bool) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if visited[rt] {
+		return false
+	}
+	visited[rt] = true
+	if reflect.PtrTo(rt).Implements(hook) {
+		return true
+	}
+	if rt.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		if _, ok := referencedTag(rt, rt.Field(i)); ok && hasHookVisited(rt.Field(i).Type, hook, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressable returns a pointer to a copy of the value if it isn't a pointer,
+// so the hooks with pointer receiver can be called and their changes are written
+func addressable(q interface{}) interface{} {
+	rv := reflect.ValueOf(q)
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		return q
+	}
+
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	return ptr.Interface()
+}
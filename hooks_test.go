@@ -0,0 +1,139 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errHookedLocked = errors.New("locked")
+
+type hookedCustomer struct {
+	Email    string         `json:"email"`
+	Locked   bool           `json:"locked"`
+	Domain   string         `json:"-"`
+	Address  *hookedAddress `json:"address" cb_referenced:"hooked_address"`
+	upserted bool
+}
+
+func (c *hookedCustomer) BeforeInsert(ctx context.Context) error {
+	c.Email = strings.ToLower(c.Email)
+	return nil
+}
+
+func (c *hookedCustomer) BeforeUpsert(ctx context.Context) error {
+	c.upserted = true
+	return c.BeforeInsert(ctx)
+}
+
+func (c *hookedCustomer) AfterGet(ctx context.Context) error {
+	c.Domain = c.Email[strings.Index(c.Email, "@")+1:]
+	return nil
+}
+
+func (c *hookedCustomer) BeforeRemove(ctx context.Context) error {
+	if c.Locked {
+		return errHookedLocked
+	}
+	return nil
+}
+
+type hookedAddress struct {
+	Country string `json:"country"`
+}
+
+func (a *hookedAddress) BeforeInsert(ctx context.Context) error {
+	a.Country = strings.ToUpper(a.Country)
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	c := hookedCustomer{
+		Email:   "Alice@Example.COM",
+		Address: &hookedAddress{Country: "hu"},
+	}
+	_, id, err := th.Insert(ctx, "hooked_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got hookedCustomer
+	if err := th.Get(ctx, "hooked_customer", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "alice@example.com", got.Email)
+	assert.Equal(t, "example.com", got.Domain)
+	assert.Equal(t, "HU", got.Address.Country)
+
+	// the hook sees the stored tree through the empty placeholder
+	got.Locked = true
+	if _, err := th.Replace(ctx, "hooked_customer", id, got, 0); err != nil {
+		t.Fatal(err)
+	}
+	var placeholder hookedCustomer
+	if err := th.Remove(ctx, "hooked_customer", id, &placeholder); err != errHookedLocked {
+		t.Errorf("error should be %s instead of %s", errHookedLocked, err)
+	}
+	assert.Equal(t, "alice@example.com", placeholder.Email)
+	assert.Equal(t, "HU", placeholder.Address.Country)
+
+	got.Locked = false
+	if _, err := th.Replace(ctx, "hooked_customer", id, got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "hooked_customer", id, &hookedCustomer{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHooksUpsertPointer(t *testing.T) {
+	c := &hookedCustomer{Email: "Bob@Example.COM"}
+	if _, _, err := th.Upsert(context.Background(), "hooked_customer", "", c, 0); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, c.upserted)
+	assert.Equal(t, "bob@example.com", c.Email)
+}
+
+func TestCallHooksOrder(t *testing.T) {
+	c := &hookedCustomer{Address: &hookedAddress{}}
+	var called []string
+	err := callHooks(c, func(model interface{}) error {
+		switch model.(type) {
+		case *hookedCustomer:
+			called = append(called, "customer")
+		case *hookedAddress:
+			called = append(called, "address")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"customer", "address"}, called)
+}
+
+func TestAddressable(t *testing.T) {
+	c := hookedCustomer{Email: "a@b.c"}
+	ptr, ok := addressable(c).(*hookedCustomer)
+	if !ok {
+		t.Fatal("value should be converted to pointer")
+	}
+	assert.Equal(t, c.Email, ptr.Email)
+
+	p := &c
+	assert.Equal(t, p, addressable(p))
+}
+
+func TestHasHook(t *testing.T) {
+	type wrapper struct {
+		Customer *hookedCustomer `json:"customer" cb_referenced:"hooked_customer"`
+	}
+	assert.True(t, hasHook(reflect.TypeOf(&hookedCustomer{}), beforeRemoverType))
+	assert.True(t, hasHook(reflect.TypeOf(&wrapper{}), beforeRemoverType))
+	assert.False(t, hasHook(reflect.TypeOf(&hookedAddress{}), beforeRemoverType))
+	assert.False(t, hasHook(reflect.TypeOf(&webshop{}), beforeRemoverType))
+}
@@ -12,9 +12,11 @@ const (
 	tagJSON       = "json"
 	tagIndexable  = "cb_indexable"
 	tagReferenced = "cb_referenced" // referenced tag represents external types for id-s
+	tagUnique     = "cb_unique"     // unique tag represents fields maintained by lookup documents
+	tagMeta       = "cb_meta"       // meta tag represents fields filled from the meta by the Get
 )
 
-//Index runs trough the given interface v and creates secondary indexes for all the with indexable:"true" tags
+// Index runs trough the given interface v and creates secondary indexes for all the with indexable:"true" tags
 func (h *Handler) Index(ctx context.Context, v interface{}) error {
 	if err := h.GetManager(ctx).CreatePrimaryIndex("", true, false); err != nil {
 		return err
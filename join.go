@@ -0,0 +1,55 @@
+package bucket
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// findJoin is a referenced document joined to its parent
+type findJoin struct {
+	alias  string
+	parent string
+	typ    string
+}
+
+// findJoins collects the referenced documents joined by the fields of a query,
+// the documents are joined once by their Go field path
+type findJoins struct {
+	aliases map[string]string
+	joins   []findJoin
+}
+
+// join returns the alias of the referenced document of the path,
+// the parent is the alias of the document containing the field
+func (j *findJoins) join(parent, path, typ string) string {
+	if alias, ok := j.aliases[path]; ok {
+		return alias
+	}
+	if j.aliases == nil {
+		j.aliases = make(map[string]string)
+	}
+
+	alias := fmt.Sprintf("c%d", len(j.joins)+1)
+	j.aliases[path] = alias
+	j.joins = append(j.joins, findJoin{alias: alias, parent: parent, typ: typ})
+	return alias
+}
+
+// findFrom returns the FROM clause of the root documents of the type with the joined
+// referenced documents, a referenced document has the id of its root with the prefix of
+// its type and the key of its parent in the _meta, the roots without it are kept
+func (h *Handler) findFrom(typ string, joins *findJoins) string {
+	bucket := h.state.configuration.BucketName
+	from := fmt.Sprintf("`%s` AS %s", bucket, findAlias)
+	if joins == nil {
+		return from
+	}
+
+	offset := len(h.state.getType(typ))
+	for _, j := range joins.joins {
+		prefix, _ := json.Marshal(h.state.getType(j.typ))
+		from += fmt.Sprintf(" LEFT JOIN `%s` AS %s ON META(%s).id = %s || SUBSTR(META(%s).id, %d) AND %s.%s = META(%s).id",
+			bucket, j.alias, j.alias, prefix, findAlias, offset, j.alias, metaParentKey, j.parent)
+	}
+	return from
+}
@@ -2,6 +2,8 @@ package bucket
 
 import (
 	"context"
+	"reflect"
+	"time"
 
 	"github.com/couchbase/gocb"
 )
@@ -13,39 +15,132 @@ type writerF func(string, string, interface{}, uint32) (gocb.Cas, error)
 // Cas is the container of Cas operation of all documents
 type Cas map[string]gocb.Cas
 
-// Remove removes a document from the bucket
+// Remove removes a document from the bucket, if the tree has BeforeRemove hooks the
+// stored tree is read into the ptr first so the hooks see the removed documents
 func (h *Handler) Remove(ctx context.Context, typ, id string, ptr interface{}) error {
-	typs, e := getDocumentTypes(ptr)
-	if e != nil {
-		return e
+	if _, err := getDocumentTypes(ptr); err != nil {
+		return err
+	}
+	m, err := h.getMeta(typ, id)
+	if err != nil {
+		return err
+	}
+	if m.DeletedAt != nil {
+		return ErrNotFound
 	}
 
-	for _, typ := range typs {
-		documentID := h.state.getDocumentKey(typ, id)
-		if _, err := h.state.bucket.Remove(documentID, 0); err != nil {
+	if hasHook(reflect.TypeOf(ptr), beforeRemoverType) {
+		if err := h.load(ctx, typ, id, ptr); err != nil {
+			return err
+		}
+		if err := beforeRemove(ctx, ptr); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	if h.state.typeOptions(typ).SoftDelete {
+		return h.markDeleted(ctx, typ, id)
+	}
+
+	return h.removeTree(ctx, h.state.getDocumentKey(typ, id), m)
 }
 
-// Touch touches documents, specifying a new expiry time for it
-// The Cas value must be 0
+// Touch touches the root, the children, lookups and revisions listed in its meta and the blobs of the tree,
+// specifying a new expiry time for them, the ptr must be a pointer of the tree's type, the missing documents
+// are skipped and the children with own ttl are handled by the TouchPolicy, gocb returns no mutation token for the
+// touches so the WithConsistency context doesn't wait for them, they change only the expiry which isn't indexed
 func (h *Handler) Touch(ctx context.Context, typ, id string, ptr interface{}, ttl uint32) error {
-	typs, e := getDocumentTypes(ptr)
-	if e != nil {
-		return e
+	if _, err := getDocumentTypes(ptr); err != nil {
+		return err
+	}
+	m, err := h.getMeta(typ, id)
+	if err != nil {
+		return err
+	}
+	if m.DeletedAt != nil && !includeDeleted(ctx) {
+		return ErrNotFound
 	}
 
-	for _, typ := range typs {
-		documentID := h.state.getDocumentKey(typ, id)
-		if _, err := h.state.bucket.Touch(documentID, 0, ttl); err != nil {
+	var ops = []gocb.BulkOp{&gocb.TouchOp{Key: h.state.getDocumentKey(typ, id), Expiry: ttl}}
+	var typs = []string{typ}
+	var owns = make(map[string]uint32)
+	for _, child := range m.ChildDocuments {
+		if expiry, ok := h.touchExpiry(child.TTL, ttl); ok {
+			ops = append(ops, &gocb.TouchOp{Key: child.Key, Expiry: expiry})
+		}
+		typs = append(typs, child.Type)
+		owns[child.Type] = child.TTL
+	}
+	// the lookups follow the document holding their unique field
+	for _, key := range m.Lookups {
+		owner, _ := h.lookupOwner(key, typs)
+		if expiry, ok := h.touchExpiry(owns[owner], ttl); ok {
+			ops = append(ops, &gocb.TouchOp{Key: key, Expiry: expiry})
+		}
+	}
+	revisions, err := h.revisionKeys(typ, id, m)
+	if err != nil {
+		return err
+	}
+	blobs, err := h.blobKeys(typ, id, m)
+	if err != nil {
+		return err
+	}
+	for _, key := range append(revisions, blobs...) {
+		ops = append(ops, &gocb.TouchOp{Key: key, Expiry: ttl})
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return err
+	}
+
+	for i, op := range ops {
+		err := op.(*gocb.TouchOp).Err
+		if err == gocb.ErrKeyNotFound && i > 0 {
+			continue
+		}
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// GetExpiry returns the expiry time of every document of the tree by their keys,
+// the zero time means the document doesn't expire
+func (h *Handler) GetExpiry(ctx context.Context, typ, id string) (map[string]time.Time, error) {
+	m, err := h.getMeta(typ, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiries = make(map[string]time.Time)
+	var keys = []string{h.state.getDocumentKey(typ, id)}
+	for _, child := range m.ChildDocuments {
+		keys = append(keys, child.Key)
+	}
+	for _, key := range keys {
+		fragment, err := h.state.bucket.LookupIn(key).GetEx(expiryXattr, gocb.SubdocFlagXattr).Execute()
+		if err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var exptime int64
+		if err := fragment.Content(expiryXattr, &exptime); err != nil {
+			return nil, err
+		}
+		if exptime == 0 {
+			expiries[key] = time.Time{}
+		} else {
+			expiries[key] = time.Unix(exptime, 0).UTC()
+		}
+	}
+
+	return expiries, nil
+}
+
 // Ping will ping a list of services and verify they are active and responding in an acceptable period of time
 func (h *Handler) Ping(ctx context.Context, services []gocb.ServiceType) (*gocb.PingReport, error) {
 	report, err := h.state.bucket.Ping(services)
@@ -54,3 +149,62 @@ func (h *Handler) Ping(ctx context.Context, services []gocb.ServiceType) (*gocb.
 	}
 	return report, nil
 }
+
+// do executes the bulk operations and returns the first error of the operations, with
+// the consistency state in the context the writes are executed one by one for their tokens
+func (h *Handler) do(ctx context.Context, ops []gocb.BulkOp) error {
+	if consistencyFromContext(ctx) != nil {
+		return h.doMt(ctx, ops)
+	}
+	if err := h.state.bucket.Do(ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if err := bulkOpError(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readError returns the first error of the bulk reads, like the failed decoding or decryption,
+// the missing children are skipped because they are optional, but the roots must exist
+func readError(ops []gocb.BulkOp, roots map[string]bool) error {
+	for _, op := range ops {
+		var key string
+		switch o := op.(type) {
+		case *gocb.GetOp:
+			key = o.Key
+		case *gocb.GetAndTouchOp:
+			key = o.Key
+		}
+		err := bulkOpError(op)
+		if err == gocb.ErrKeyNotFound && !roots[key] {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bulkOpError(op gocb.BulkOp) error {
+	switch o := op.(type) {
+	case *gocb.GetOp:
+		return o.Err
+	case *gocb.GetAndTouchOp:
+		return o.Err
+	case *gocb.TouchOp:
+		return o.Err
+	case *gocb.RemoveOp:
+		return o.Err
+	case *gocb.UpsertOp:
+		return o.Err
+	case *gocb.InsertOp:
+		return o.Err
+	case *gocb.ReplaceOp:
+		return o.Err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package bucket
+
+import (
+	"context"
+	"sync"
+
+	"github.com/couchbase/gocb"
+)
+
+// Exists checks the document exists by sub-document lookups without reading its body,
+// the soft deleted trees don't exist without IncludeDeleted, with VerifyChildren
+// every child listed in the meta must exist too
+func (h *Handler) Exists(ctx context.Context, typ, id string) (bool, error) {
+	m, err := h.lookupMeta(ctx, typ, id)
+	if err != nil || m == nil {
+		return false, err
+	}
+	if m.DeletedAt != nil && !includeDeleted(ctx) {
+		return false, nil
+	}
+	if !verifyChildren(ctx) {
+		return true, nil
+	}
+
+	for _, child := range m.ChildDocuments {
+		_, err := h.state.bucket.LookupIn(child.Key).ExistsEx(expiryXattr, gocb.SubdocFlagXattr).Execute()
+		if err == gocb.ErrKeyNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ExistsMulti checks the existence of the documents like Exists with the LoadConcurrency
+// of the configuration, the result is keyed by the ids
+func (h *Handler) ExistsMulti(ctx context.Context, typ string, ids []string) (map[string]bool, error) {
+	var (
+		result   = make(map[string]bool, len(ids))
+		sem      = make(chan struct{}, h.loadConcurrency())
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			wg.Wait()
+			return nil, err
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			exists, err := h.Exists(ctx, typ, id)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			result[id] = exists
+		}(id)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// lookupMeta reads the deletion time and the children of the root by sub-document lookup,
+// the binary documents don't support it so their whole meta is read, nil means missing document
+func (h *Handler) lookupMeta(ctx context.Context, typ, id string) (*meta, error) {
+	if !h.state.binary(typ) {
+		fragment, err := h.state.bucket.LookupIn(h.state.getDocumentKey(typ, id)).
+			Get(metaDeletedAt).
+			Get(metaChildren).
+			Execute()
+		switch {
+		case err == gocb.ErrKeyNotFound:
+			return nil, nil
+		case err == nil || err == gocb.ErrSubDocBadMulti:
+			if fragment.ContentByIndex(0, nil) != gocb.ErrSubDocNotJson {
+				var m meta
+				if err := fragment.Content(metaDeletedAt, &m.DeletedAt); err != nil && err != gocb.ErrSubDocPathNotFound {
+					return nil, err
+				}
+				if err := fragment.Content(metaChildren, &m.ChildDocuments); err != nil && err != gocb.ErrSubDocPathNotFound {
+					return nil, err
+				}
+				return &m, nil
+			}
+		case err != gocb.ErrSubDocNotJson:
+			return nil, err
+		}
+	}
+
+	// the documents written with other codec before are binary documents as well
+	m, err := h.getMeta(typ, id)
+	if err == gocb.ErrKeyNotFound {
+		return nil, nil
+	}
+	return m, err
+}
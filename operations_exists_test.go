@@ -0,0 +1,118 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := th.Exists(ctx, "webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+
+	exists, err = th.Exists(ctx, "webshop", xid.New().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, exists)
+}
+
+func TestExistsVerifyChildren(t *testing.T) {
+	ctx := VerifyChildren(context.Background())
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := th.Exists(ctx, "webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+
+	if _, err := th.state.bucket.Remove(th.state.getDocumentKey("store", id), 0); err != nil {
+		t.Fatal(err)
+	}
+	exists, err = th.Exists(ctx, "webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, exists)
+
+	exists, err = th.Exists(context.Background(), "webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+}
+
+func TestExistsSoftDeleted(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := th.Insert(ctx, "soft_webshop", "", generate(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := th.Exists(ctx, "soft_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, exists)
+
+	exists, err = th.Exists(IncludeDeleted(ctx), "soft_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+}
+
+func TestExistsBinaryDocument(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := th.Insert(ctx, "msgpack_webshop", "", generate(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := th.Exists(VerifyChildren(ctx), "msgpack_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+}
+
+func TestExistsMulti(t *testing.T) {
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := xid.New().String()
+
+	result, err := th.ExistsMulti(context.Background(), "webshop", []string{id, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]bool{id: true, missing: false}, result)
+}
+
+func TestExistsMultiCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := th.ExistsMulti(ctx, "webshop", []string{xid.New().String()}); err != context.Canceled {
+		t.Errorf("error should be %s instead of %v", context.Canceled, err)
+	}
+}
@@ -9,20 +9,41 @@ import (
 
 // Get retrieves a document from the bucket
 func (h *Handler) Get(ctx context.Context, typ, id string, ptr interface{}) error {
+	if err := h.load(ctx, typ, id, ptr); err != nil {
+		return err
+	}
+
+	return afterGet(ctx, ptr)
+}
+
+// load reads the tree into the ptr without calling the AfterGet hooks
+func (h *Handler) load(ctx context.Context, typ, id string, ptr interface{}) error {
 	kv, err := h.get(ctx, typ, id, ptr)
 	if err != nil {
 		return err
 	}
 
 	var ops []gocb.BulkOp
+	var values []interface{}
 	for k, v := range kv {
-		ops = append(ops, &gocb.GetOp{Key: k.Key, Value: v})
+		value := h.newDocumentReader(k.Key, v)
+		ops = append(ops, &gocb.GetOp{Key: k.Key, Value: value})
+		values = append(values, value)
+	}
+
+	if err := h.state.bucket.Do(ops); err != nil {
+		return err
 	}
+	if err := readError(ops, map[string]bool{h.state.getDocumentKey(typ, id): true}); err != nil {
+		return err
+	}
+	finishReaders(values...)
 
-	return h.state.bucket.Do(ops)
+	return nil
 }
 
-// GetAndTouch retrieves a document and simultaneously updates its expiry times
+// GetAndTouch retrieves a document and simultaneously updates its expiry times,
+// the children with own ttl are handled by the TouchPolicy of the configuration
 func (h *Handler) GetAndTouch(ctx context.Context, typ, id string, ptr interface{}, ttl uint32) error {
 	kv, err := h.get(ctx, typ, id, ptr)
 	if err != nil {
@@ -30,11 +51,52 @@ func (h *Handler) GetAndTouch(ctx context.Context, typ, id string, ptr interface
 	}
 
 	var ops []gocb.BulkOp
+	var values []interface{}
 	for k, v := range kv {
-		ops = append(ops, &gocb.GetAndTouchOp{Key: k.Key, Value: v, Expiry: ttl})
+		value := h.newDocumentReader(k.Key, v)
+		if expiry, ok := h.touchExpiry(k.TTL, ttl); ok {
+			ops = append(ops, &gocb.GetAndTouchOp{Key: k.Key, Value: value, Expiry: expiry})
+		} else {
+			ops = append(ops, &gocb.GetOp{Key: k.Key, Value: value})
+		}
+		values = append(values, value)
+	}
+
+	if err := h.state.bucket.Do(ops); err != nil {
+		return err
+	}
+	if err := readError(ops, map[string]bool{h.state.getDocumentKey(typ, id): true}); err != nil {
+		return err
+	}
+	finishReaders(values...)
+
+	return afterGet(ctx, ptr)
+}
+
+// GetBy retrieves a document by the value of a unique field through its lookup document,
+// the field is the json name of a field tagged with cb_unique:"true", it returns ErrNotFound
+// if the lookup document or the tree is missing
+func (h *Handler) GetBy(ctx context.Context, typ, field string, value interface{}, ptr interface{}) error {
+	if err := h.inputcheck(ptr); err != nil {
+		return err
+	}
+
+	var l lookup
+	key := h.lookupDocumentKey(typ, field, value)
+	if _, err := h.state.bucket.Get(key, &l); err != nil {
+		if err == gocb.ErrKeyNotFound {
+			return ErrNotFound
+		}
+		return err
 	}
 
-	return h.state.bucket.Do(ops)
+	if err := h.Get(ctx, typ, l.ID, ptr); err != nil {
+		if err == gocb.ErrKeyNotFound {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 func (h *Handler) get(ctx context.Context, typ, id string, ptr interface{}) (map[documentMeta]interface{}, error) {
@@ -91,6 +153,9 @@ func (h *Handler) availableDocuments(tx context.Context, typ, id string, ptr int
 	if err != nil {
 		return nil, err
 	}
+	if m.DeletedAt != nil && !includeDeleted(tx) {
+		return nil, ErrNotFound
+	}
 
 	for _, rdm := range m.ChildDocuments {
 		kv[rdm] = nil
@@ -125,7 +190,7 @@ func (h *Handler) lookForNestedFields(ptr interface{}, fields map[string]interfa
 		if rvQField.Kind() == reflect.Ptr {
 			var cont bool
 			var err error
-			fields, cont, err = h.gfield(rvQField, rtQField, fields)
+			fields, cont, err = h.gfield(rvQField, rt, rtQField, fields)
 			if err != nil {
 				return nil, err
 			}
@@ -139,8 +204,8 @@ func (h *Handler) lookForNestedFields(ptr interface{}, fields map[string]interfa
 }
 
 // gfieldcheck checks the certain field in Get method
-func (h *Handler) gfieldcheck(rvQField reflect.Value, rtQField reflect.StructField, fields map[string]interface{}) (string, bool, error) {
-	refTag, hasRefTag := rtQField.Tag.Lookup(tagReferenced)
+func (h *Handler) gfieldcheck(rvQField reflect.Value, rt reflect.Type, rtQField reflect.StructField, fields map[string]interface{}) (string, bool, error) {
+	refTag, hasRefTag := referencedTag(rt, rtQField)
 
 	// if the struct isn't referenced or it's referenced but it's not a struct
 	// see more: Rule #1
@@ -161,9 +226,9 @@ func (h *Handler) gfieldcheck(rvQField reflect.Value, rtQField reflect.StructFie
 	return refTag, false, nil
 }
 
-func (h *Handler) gfield(rvQField reflect.Value, rtQField reflect.StructField, fields map[string]interface{}) (map[string]interface{}, bool, error) {
+func (h *Handler) gfield(rvQField reflect.Value, rt reflect.Type, rtQField reflect.StructField, fields map[string]interface{}) (map[string]interface{}, bool, error) {
 	// check field
-	refTag, cont, err := h.gfieldcheck(rvQField, rtQField, fields)
+	refTag, cont, err := h.gfieldcheck(rvQField, rt, rtQField, fields)
 	if err != nil {
 		return fields, false, err
 	}
@@ -3,33 +3,61 @@ package bucket
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/couchbase/gocb"
+	"github.com/golang/protobuf/proto"
 	"github.com/rs/xid"
 )
 
+// Insert inserts a new document to the bucket, the referenced fields stored as separate documents
 func (h *Handler) Insert(ctx context.Context, typ, id string, q interface{}, ttl uint32) (Cas, string, error) {
 	if id == "" {
 		id = xid.New().String()
 	}
 
-	kv := h.getSubDocuments(typ, id, q, nil)
+	q = addressable(q)
+	if err := beforeInsert(ctx, q); err != nil {
+		return nil, id, err
+	}
+	if err := validate(q); err != nil {
+		return nil, id, err
+	}
+
+	kv, err := h.getSubDocuments(typ, id, q, nil, 0)
+	if err != nil {
+		return nil, id, err
+	}
+
+	lookups := documentLookups(kv, typ)
+	reserved, err := h.reserveLookups(ctx, typ, id, lookups, kv, ttl)
+	if err != nil {
+		return nil, id, err
+	}
 
 	var ops []gocb.BulkOp
 	for k, v := range kv {
 		key := h.state.getDocumentKey(k, id)
-		ops = append(ops, &gocb.InsertOp{Key: key, Value: v, Expiry: ttl})
+		ops = append(ops, &gocb.InsertOp{Key: key, Value: h.encode(k, v), Expiry: documentExpiry(v, ttl)})
 	}
 
-	err := h.state.bucket.Do(ops)
-	return nil, id, err
+	if err := h.do(ctx, ops); err != nil {
+		h.releaseLookups(ctx, reserved)
+		return nil, id, err
+	}
+	return nil, id, nil
 }
 
-func (h *Handler) getSubDocuments(typ, id string, q interface{}, parent *documentMeta) map[string]map[string]interface{} {
+// getSubDocuments collects the documents of the tree, the ttl is the own ttl of the document, 0 for the root
+func (h *Handler) getSubDocuments(typ, id string, q interface{}, parent *documentMeta, ttl uint32) (map[string]map[string]interface{}, error) {
 	var documents = make(map[string]map[string]interface{})
+	var now = time.Now().UTC()
 	var metaField = &meta{
 		ParentDocument: parent,
 		Type:           typ,
+		TTL:            ttl,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
 	var rv = reflect.ValueOf(q)
@@ -37,41 +65,85 @@ func (h *Handler) getSubDocuments(typ, id string, q interface{}, parent *documen
 
 	if rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
-			return documents
+			return documents, nil
 		}
 		rv = reflect.Indirect(rv)
 		rt = rv.Type()
 	}
 
+	if msg, ok := q.(proto.Message); ok && rt.Kind() == reflect.Struct {
+		if err := h.getProtoDocuments(typ, id, msg, metaField, documents); err != nil {
+			return nil, err
+		}
+		return documents, nil
+	}
+	if h.state.codec(typ) == CodecProtobuf {
+		return nil, ErrProtobufCodecModel
+	}
+
 	var fields = make(map[string]interface{})
 	for i := 0; i < rt.NumField(); i++ {
 		rvField := rv.Field(i)
 		rtField := rt.Field(i)
-		if tag, ok := rtField.Tag.Lookup(tagReferenced); ok {
-			h.buildDocuments(typ, id, tag, rvField.Interface(), metaField, documents)
+		if _, ok := rtField.Tag.Lookup(tagMeta); ok {
+			continue
+		}
+		if tag, ok := referencedTag(rt, rtField); ok {
+			if err := h.buildDocuments(typ, id, tag, rvField.Interface(), rtField, metaField, documents); err != nil {
+				return nil, err
+			}
 		} else {
 			if j, ok := rtField.Tag.Lookup(tagJSON); ok && j != "-" {
-				fields[removeOmitempty(j)] = rvField.Interface()
+				var value = rvField.Interface()
+				name, encrypted, err := encryptionKeyName(rtField)
+				if err != nil {
+					return nil, err
+				}
+				if encrypted {
+					if value, err = h.encryptValue(name, h.state.getDocumentKey(typ, id), removeOmitempty(j), value); err != nil {
+						return nil, err
+					}
+				}
+				fields[removeOmitempty(j)] = value
+				if key, ok := h.lookupKey(typ, removeOmitempty(j), rvField, rtField); ok {
+					metaField.AddLookup(key)
+				}
 			}
 		}
 	}
 	fields[metaFieldName] = metaField
 	documents[typ] = fields
 
-	return documents
+	return documents, nil
 }
 
-func (h *Handler) buildDocuments(typ, id, tag string, sub interface{}, metaField *meta, documents map[string]map[string]interface{}) {
+func (h *Handler) buildDocuments(typ, id, tag string, sub interface{}, field reflect.StructField, metaField *meta, documents map[string]map[string]interface{}) error {
 	currentKey := h.state.getDocumentKey(typ, id)
 	current := documentMeta{
 		Type: typ,
 		ID:   id,
 		Key:  currentKey,
 	}
-	subDocuments := h.getSubDocuments(tag, id, sub, &current)
+	ttl, err := fieldTTL(field, metaField.TTL)
+	if err != nil {
+		return err
+	}
+	subDocuments, err := h.getSubDocuments(tag, id, sub, &current, ttl)
+	if err != nil {
+		return err
+	}
 	for k, v := range subDocuments {
 		childKey := h.state.getDocumentKey(k, id)
-		metaField.AddChildDocument(childKey, k, id)
+		var childTTL uint32
+		if child, ok := v[metaFieldName].(*meta); ok {
+			childTTL = child.TTL
+		}
+		metaField.AddChildDocument(childKey, k, id, childTTL)
 		documents[k] = v
 	}
+	if child, ok := subDocuments[tag][metaFieldName].(*meta); ok {
+		metaField.Lookups = append(metaField.Lookups, child.Lookups...)
+	}
+
+	return nil
 }
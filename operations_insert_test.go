@@ -15,7 +15,10 @@ import (
 func TestHandler_GetSubDocuments(t *testing.T) {
 	var ws = generate()
 
-	resultset := th.getSubDocuments("webshop", xid.New().String(), ws, nil)
+	resultset, err := th.getSubDocuments("webshop", xid.New().String(), ws, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for k, v := range resultset {
 		shit, _ := json.Marshal(v)
 		fmt.Printf("k: %s, v: %s\n", k, shit)
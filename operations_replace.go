@@ -0,0 +1,25 @@
+package bucket
+
+import (
+	"context"
+
+	"github.com/couchbase/gocb"
+)
+
+// Replace replaces an existing document in the bucket,
+// every document of the tree must exist
+func (h *Handler) Replace(ctx context.Context, typ, id string, q interface{}, ttl uint32) (Cas, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	q = addressable(q)
+	if err := beforeReplace(ctx, q); err != nil {
+		return nil, err
+	}
+
+	err := h.write(ctx, typ, id, q, func(key string, value interface{}, expiry uint32) gocb.BulkOp {
+		return &gocb.ReplaceOp{Key: key, Value: value, Expiry: expiry}
+	}, ttl)
+	return nil, err
+}
@@ -0,0 +1,41 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/couchbase/gocb"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws := generate()
+	if _, err := th.Replace(ctx, "webshop", id, ws, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	got := webshop{}
+	if err := th.Get(ctx, "webshop", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws, got)
+}
+
+func TestReplaceNotFoundExpectError(t *testing.T) {
+	if _, err := th.Replace(context.Background(), "webshop", xid.New().String(), generate(), 0); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+	}
+}
+
+func TestReplaceEmptyIDExpectError(t *testing.T) {
+	if _, err := th.Replace(context.Background(), "webshop", "", generate(), 0); err != ErrEmptyID {
+		t.Errorf("error should be %s instead of %s", ErrEmptyID, err)
+	}
+}
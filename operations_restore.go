@@ -0,0 +1,110 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/couchbase/gocb"
+)
+
+// Restore undoes the soft delete of a tree
+func (h *Handler) Restore(ctx context.Context, typ, id string) error {
+	m, err := h.getMeta(typ, id)
+	if err != nil {
+		return err
+	}
+	if m.DeletedAt == nil {
+		return nil
+	}
+	if h.state.binary(typ) {
+		return ErrSubdocumentCodec
+	}
+
+	fragment, err := h.state.bucket.MutateIn(h.state.getDocumentKey(typ, id), 0, 0).
+		Remove(metaDeletedAt).
+		Execute()
+	if err != nil {
+		return err
+	}
+	recordMutation(ctx, fragment.MutationToken())
+	return nil
+}
+
+// PurgeDeleted removes the trees of the type which were soft deleted before the
+// retention window and returns the number of removed trees, it should run periodically
+func (h *Handler) PurgeDeleted(ctx context.Context, typ string, retention time.Duration) (int, error) {
+	queryStr := fmt.Sprintf("SELECT RAW META().id FROM `%s` WHERE META().id LIKE $1 AND _meta._type = $2 AND STR_TO_MILLIS(_meta._deleted_at) < $3",
+		h.state.configuration.BucketName)
+	cutoff := time.Now().Add(-retention).UnixNano() / int64(time.Millisecond)
+	rows, err := h.query(ctx, queryStr, []interface{}{h.state.getType(typ) + "%", typ, cutoff})
+	if err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	var key string
+	for rows.Next(&key) {
+		keys = append(keys, key)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	var purged int
+	for _, key := range keys {
+		id := h.state.fetchDocumentIdentifier(key)
+		m, err := h.getMeta(typ, id)
+		if err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		// it could be restored since the query
+		if m.DeletedAt == nil {
+			continue
+		}
+		if err := h.removeTree(ctx, key, m); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// markDeleted marks the root's meta as deleted
+func (h *Handler) markDeleted(ctx context.Context, typ, id string) error {
+	if h.state.binary(typ) {
+		return ErrSubdocumentCodec
+	}
+
+	fragment, err := h.state.bucket.MutateIn(h.state.getDocumentKey(typ, id), 0, 0).
+		Upsert(metaDeletedAt, time.Now().UTC(), false).
+		Execute()
+	if err != nil {
+		return err
+	}
+	recordMutation(ctx, fragment.MutationToken())
+	return nil
+}
+
+// removeTree removes the root, the children, lookups and revisions listed in the root's meta with the blobs of the tree
+func (h *Handler) removeTree(ctx context.Context, key string, m *meta) error {
+	for _, child := range m.ChildDocuments {
+		if err := h.remove(ctx, child.Key); err != nil && err != gocb.ErrKeyNotFound {
+			return err
+		}
+	}
+	if err := h.remove(ctx, key); err != nil {
+		return err
+	}
+	h.releaseLookups(ctx, m.Lookups)
+	id := h.state.fetchDocumentIdentifier(key)
+	if err := h.removeBlobs(ctx, m.Type, id, m); err != nil {
+		return err
+	}
+
+	return h.removeRevisions(ctx, m.Type, id, m)
+}
@@ -0,0 +1,166 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocb"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSoftInsert() (webshop, string, error) {
+	ws := generate()
+	_, id, err := th.Insert(context.Background(), "soft_webshop", "", ws, 0)
+	return ws, id, err
+}
+
+func TestSoftRemove(t *testing.T) {
+	ctx := context.Background()
+	ws, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := th.Get(ctx, "soft_webshop", id, &webshop{}); err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+
+	got := webshop{}
+	if err := th.Get(IncludeDeleted(ctx), "soft_webshop", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws, got)
+
+	m, err := th.getMeta("soft_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, m.DeletedAt)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	ws, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Restore(ctx, "soft_webshop", id); err != nil {
+		t.Fatal(err)
+	}
+
+	got := webshop{}
+	if err := th.Get(ctx, "soft_webshop", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws, got)
+}
+
+func TestRestoreNotDeleted(t *testing.T) {
+	_, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Restore(context.Background(), "soft_webshop", id); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	purged, err := th.PurgeDeleted(ctx, "soft_webshop", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged < 1 {
+		t.Errorf("purged should be at least 1, instead of %d", purged)
+	}
+
+	if err := th.Get(IncludeDeleted(ctx), "soft_webshop", id, &webshop{}); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+	}
+	if _, err := th.getMeta("product", id); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+	}
+}
+
+func TestUpsertSoftDeletedExpectError(t *testing.T) {
+	ctx := context.Background()
+	ws, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", id, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := th.Upsert(ctx, "soft_webshop", id, ws, 0); err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+	if _, err := th.Replace(ctx, "soft_webshop", id, ws, 0); err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+
+	m, err := th.getMeta("soft_webshop", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, m.DeletedAt)
+
+	if err := th.Restore(ctx, "soft_webshop", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := th.Upsert(ctx, "soft_webshop", id, ws, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetBulkSoftDeleted(t *testing.T) {
+	ctx := context.Background()
+	ws, id, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, deletedID, err := testSoftInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "soft_webshop", deletedID, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+
+	hits := []gocb.SearchResultHit{
+		{Id: th.state.getDocumentKey("soft_webshop", deletedID)},
+		{Id: th.state.getDocumentKey("soft_webshop", id)},
+	}
+	got := make([]webshop, len(hits))
+	if err := th.GetBulk(ctx, hits, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []webshop{{}, ws}, got)
+
+	got = make([]webshop, len(hits))
+	if err := th.GetBulk(IncludeDeleted(ctx), hits, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, got, 2)
+	assert.NotEmpty(t, got[0].Token)
+}
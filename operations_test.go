@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rs/xid"
 	"github.com/volatiletech/null"
@@ -91,14 +92,28 @@ func TestPingAllService(t *testing.T) {
 }
 
 func TestTouch(t *testing.T) {
-	ws, ID, err := testInsert()
+	_, ID, err := testInsert()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if err := th.Touch(context.Background(), "webshop", ID, &ws, 10); err != nil {
+	if err := th.Touch(context.Background(), "webshop", ID, &webshop{}, 10); err != nil {
 		t.Error("error", err)
 	}
+
+	expiries, err := th.GetExpiry(context.Background(), "webshop", ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := th.getMeta("webshop", ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, expiries, len(m.ChildDocuments)+1)
+	for key, expiry := range expiries {
+		assert.False(t, expiry.IsZero(), key)
+		assert.True(t, expiry.Before(time.Now().Add(time.Minute)), key)
+	}
 }
 
 func TestTouchNonPointerInputExpectError(t *testing.T) {
@@ -111,6 +126,88 @@ func TestTouchNonPointerInputExpectError(t *testing.T) {
 	}
 }
 
+func TestTouchLookupsAndRevisions(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	_, customerID, err := th.Insert(ctx, "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Touch(ctx, "unique_customer", customerID, &uniqueCustomer{}, 10); err != nil {
+		t.Fatal(err)
+	}
+	m, err := th.getMeta("unique_customer", customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, m.Lookups, 2)
+	for _, key := range m.Lookups {
+		assert.NotZero(t, keyExpiry(t, key), key)
+	}
+
+	ws := generate()
+	_, id, err := th.Insert(ctx, "audit_webshop", "", ws, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := ws
+	updated.Status = "shipped"
+	if _, _, err := th.Upsert(ctx, "audit_webshop", id, updated, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Touch(ctx, "audit_webshop", id, &webshop{}, 10); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotZero(t, keyExpiry(t, th.revisionCounterKey("audit_webshop", id)))
+	assert.NotZero(t, keyExpiry(t, th.revisionKey("audit_webshop", id, 1)))
+}
+
+// keyExpiry returns the expiry of the document in unix time, 0 if it doesn't expire
+func keyExpiry(t *testing.T, key string) int64 {
+	fragment, err := th.state.bucket.LookupIn(key).GetEx(expiryXattr, gocb.SubdocFlagXattr).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exptime int64
+	if err := fragment.Content(expiryXattr, &exptime); err != nil {
+		t.Fatal(err)
+	}
+	return exptime
+}
+
+func TestTouchMissingChild(t *testing.T) {
+	_, ID, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.state.bucket.Remove(th.state.getDocumentKey("store", ID), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := th.Touch(context.Background(), "webshop", ID, &webshop{}, 10); err != nil {
+		t.Error("error", err)
+	}
+	expiries, err := th.GetExpiry(context.Background(), "webshop", ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, expiries, th.state.getDocumentKey("store", ID))
+	assert.False(t, expiries[th.state.getDocumentKey("webshop", ID)].IsZero())
+}
+
+func TestGetExpiryWithoutTTL(t *testing.T) {
+	_, ID, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiries, err := th.GetExpiry(context.Background(), "webshop", ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, expiries[th.state.getDocumentKey("webshop", ID)].IsZero())
+}
+
 func TestUpsertNewID(t *testing.T) {
 	if _, id, err := testUpsert(xid.New().String()); err != nil || id == "" {
 		t.Fatal(err)
@@ -189,6 +286,25 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestRemoveMissingChild(t *testing.T) {
+	_, ID, err := testInsert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.state.bucket.Remove(th.state.getDocumentKey("store", ID), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := th.Remove(context.Background(), "webshop", ID, &webshop{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{"webshop", "product", "origin"} {
+		if _, err := th.getMeta(typ, ID); err != gocb.ErrKeyNotFound {
+			t.Errorf("%s should be removed, error: %v", typ, err)
+		}
+	}
+}
+
 func TestRemoveInvalidInput(t *testing.T) {
 	_, ID, err := testInsert()
 	if err != nil {
@@ -13,14 +13,78 @@ func (h *Handler) Upsert(ctx context.Context, typ, id string, q interface{}, ttl
 		id = xid.New().String()
 	}
 
-	kv := h.getSubDocuments(typ, id, q, nil)
+	q = addressable(q)
+	if err := beforeUpsert(ctx, q); err != nil {
+		return nil, id, err
+	}
+
+	err := h.write(ctx, typ, id, q, func(key string, value interface{}, expiry uint32) gocb.BulkOp {
+		return &gocb.UpsertOp{Key: key, Value: value, Expiry: expiry}
+	}, ttl)
+	return nil, id, err
+}
+
+// write stores the documents of the tree with the operation built by the opF, it keeps
+// the creation time, the lookup documents and the revision history of the tree up to date
+func (h *Handler) write(ctx context.Context, typ, id string, q interface{}, opF func(string, interface{}, uint32) gocb.BulkOp, ttl uint32) error {
+	if err := validate(q); err != nil {
+		return err
+	}
+
+	kv, err := h.getSubDocuments(typ, id, q, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	stored, err := h.storedMetas(typ, id, kv)
+	if err != nil {
+		return err
+	}
+	// the soft deleted tree must be restored first, otherwise the write would resurrect it
+	if m, ok := stored[typ]; ok && m.DeletedAt != nil {
+		return ErrNotFound
+	}
+	for k, m := range stored {
+		if dm, ok := kv[k][metaFieldName].(*meta); ok && !m.CreatedAt.IsZero() {
+			dm.CreatedAt = m.CreatedAt
+		}
+	}
+
+	// the lookups of the new unique values reserved before the write and
+	// the previous ones released after it, so the unique values move together
+	var previous []string
+	if m, ok := stored[typ]; ok {
+		previous = m.Lookups
+	}
+	// the whole previous version is read only for the revisions, they are stored before the
+	// write, so the previous version isn't lost if storing them fails after the overwrite
+	if h.state.typeOptions(typ).History {
+		current, err := h.currentDocuments(id, kv)
+		if err != nil {
+			return err
+		}
+		if err := h.storeRevisions(ctx, id, current, kv, ttl); err != nil {
+			return err
+		}
+	}
+
+	lookups := documentLookups(kv, typ)
+	reserved, err := h.reserveLookups(ctx, typ, id, lookups, kv, ttl)
+	if err != nil {
+		return err
+	}
 
 	var ops []gocb.BulkOp
 	for k, v := range kv {
 		key := h.state.getDocumentKey(k, id)
-		ops = append(ops, &gocb.UpsertOp{Key: key, Value: v, Expiry: ttl})
+		ops = append(ops, opF(key, h.encode(k, v), documentExpiry(v, ttl)))
 	}
 
-	err := h.state.bucket.Do(ops)
-	return nil, id, err
+	if err := h.do(ctx, ops); err != nil {
+		h.releaseLookups(ctx, reserved)
+		return err
+	}
+	h.releaseLookups(ctx, staleLookups(previous, lookups))
+
+	return nil
 }
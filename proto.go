@@ -0,0 +1,223 @@
+package bucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const (
+	tagProtobuf = "protobuf" // protobuf tag represents the field of a generated message
+)
+
+// E_Referenced is the (bucket.referenced) field option defined in bucket.proto,
+// it marks a message field as a referenced document like the cb_referenced tag
+var E_Referenced = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         50601,
+	Name:          "bucket.referenced",
+	Tag:           "bytes,50601,opt,name=referenced",
+	Filename:      "bucket.proto",
+}
+
+func init() {
+	proto.RegisterExtension(E_Referenced)
+}
+
+// protoReferences caches the referenced fields of the message types by their Go field names
+var protoReferences sync.Map
+
+// protoMarshaler encodes the messages with their proto field names,
+// the oneofs and the well-known types are encoded by their canonical JSON form
+var protoMarshaler = jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+
+// protoUnmarshaler ignores the fields of the document aren't part of the message, like the _meta
+var protoUnmarshaler = jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+// referencedTag returns the type of the referenced document from the cb_referenced
+// tag or the (bucket.referenced) option of the field, rt is the type of the parent struct
+func referencedTag(rt reflect.Type, field reflect.StructField) (string, bool) {
+	if tag, ok := field.Tag.Lookup(tagReferenced); ok {
+		return tag, true
+	}
+	tag, ok := protoReferencedFields(rt)[field.Name]
+	return tag, ok
+}
+
+func protoReferencedFields(rt reflect.Type) map[string]string {
+	if v, ok := protoReferences.Load(rt); ok {
+		return v.(map[string]string)
+	}
+
+	var fields = make(map[string]string)
+	if msg, ok := reflect.New(rt).Interface().(descriptor.Message); ok {
+		var options = make(map[string]string)
+		_, md := descriptor.ForMessage(msg)
+		for _, f := range md.GetField() {
+			if f.GetOptions() == nil {
+				continue
+			}
+			ext, err := proto.GetExtension(f.GetOptions(), E_Referenced)
+			if err != nil {
+				continue
+			}
+			if tag, ok := ext.(*string); ok {
+				options[f.GetName()] = *tag
+			}
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			if tag, ok := options[protoName(rt.Field(i))]; ok {
+				fields[rt.Field(i).Name] = tag
+			}
+		}
+	}
+
+	protoReferences.Store(rt, fields)
+	return fields
+}
+
+// protoName returns the proto field name of a generated message's field
+func protoName(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get(tagProtobuf), ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return ""
+}
+
+// getProtoDocuments collects the documents of a protobuf message, the own fields of the
+// message are encoded by jsonpb and the referenced ones are stored as separate documents
+func (h *Handler) getProtoDocuments(typ, id string, msg proto.Message, metaField *meta, documents map[string]map[string]interface{}) error {
+	rv := reflect.ValueOf(msg).Elem()
+	rt := rv.Type()
+
+	// the referenced fields are cleared in a copy, so they aren't encoded into the parent
+	clone := proto.Clone(msg)
+	rvClone := reflect.ValueOf(clone).Elem()
+
+	var referenced []string
+	var encrypted = make(map[string]string)
+	for i := 0; i < rt.NumField(); i++ {
+		rtField := rt.Field(i)
+		name := protoName(rtField)
+		if tag, ok := referencedTag(rt, rtField); ok {
+			if err := h.buildDocuments(typ, id, tag, rv.Field(i).Interface(), rtField, metaField, documents); err != nil {
+				return err
+			}
+			rvClone.Field(i).Set(reflect.Zero(rtField.Type))
+			referenced = append(referenced, name)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		key, ok, err := encryptionKeyName(rtField)
+		if err != nil {
+			return err
+		}
+		if ok {
+			encrypted[name] = key
+		}
+		if key, ok := h.lookupKey(typ, name, rv.Field(i), rtField); ok {
+			metaField.AddLookup(key)
+		}
+	}
+
+	// the protobuf codec stores the message itself, so it can't hold the encrypted values
+	if h.state.codec(typ) == CodecProtobuf {
+		if len(encrypted) > 0 {
+			return ErrProtobufCodecModel
+		}
+		metaField.Proto = registerProtoType(msg)
+	}
+
+	fields, err := protoFields(clone)
+	if err != nil {
+		return err
+	}
+	for _, name := range referenced {
+		delete(fields, name)
+	}
+	for name, key := range encrypted {
+		if fields[name], err = h.encryptValue(key, h.state.getDocumentKey(typ, id), name, fields[name]); err != nil {
+			return err
+		}
+	}
+	fields[metaFieldName] = metaField
+	documents[typ] = fields
+
+	return nil
+}
+
+// protoFields encodes the message to the fields of a document
+func protoFields(msg proto.Message) (map[string]interface{}, error) {
+	var buf bytes.Buffer
+	if err := protoMarshaler.Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// protoReader decodes a document into a protobuf message by jsonpb,
+// the ptr is a pointer to the message or a pointer to the message's pointer
+type protoReader struct {
+	h         *Handler
+	key       string
+	ptr       interface{}
+	encrypted bool
+}
+
+// isProtoMessage checks the value is a *T or **T where *T is a protobuf message
+func isProtoMessage(ptr interface{}) bool {
+	rt := reflect.TypeOf(ptr)
+	if rt == nil || rt.Kind() != reflect.Ptr {
+		return false
+	}
+	if rt.Elem().Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt.Implements(reflect.TypeOf((*proto.Message)(nil)).Elem()) && rt.Elem().Kind() == reflect.Struct
+}
+
+// protoMessageOf returns a new message of the *T or **T type where *T is a protobuf message
+func protoMessageOf(rt reflect.Type) proto.Message {
+	rt = rt.Elem()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return reflect.New(rt).Interface().(proto.Message)
+}
+
+func (r *protoReader) UnmarshalJSON(data []byte) error {
+	if r.encrypted {
+		var err error
+		if data, err = r.h.decryptDocument(r.key, data); err != nil {
+			return err
+		}
+	}
+
+	rv := reflect.ValueOf(r.ptr)
+	if rv.Elem().Kind() == reflect.Ptr {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	return protoUnmarshaler.Unmarshal(bytes.NewReader(data), rv.Interface().(proto.Message))
+}
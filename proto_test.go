@@ -0,0 +1,213 @@
+package bucket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+)
+
+// protoAccount and protoAddress are written like the protoc-gen-go output of:
+//
+//	message Account {
+//	    string display_name = 1;
+//	    google.protobuf.Timestamp created = 2;
+//	    google.protobuf.Duration session = 3;
+//	    google.protobuf.StringValue nickname = 4;
+//	    oneof contact {
+//	        string email = 5;
+//	        string phone = 6;
+//	    }
+//	    Address address = 7 [(bucket.referenced) = "proto_account_address"];
+//	}
+//
+//	message Address {
+//	    string city = 1;
+//	}
+type protoAccount struct {
+	DisplayName          string                `protobuf:"bytes,1,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Created              *timestamp.Timestamp  `protobuf:"bytes,2,opt,name=created,proto3" json:"created,omitempty"`
+	Session              *duration.Duration    `protobuf:"bytes,3,opt,name=session,proto3" json:"session,omitempty"`
+	Nickname             *wrappers.StringValue `protobuf:"bytes,4,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Contact              isProtoAccountContact `protobuf_oneof:"contact"`
+	Address              *protoAddress         `protobuf:"bytes,7,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *protoAccount) Reset()         { *m = protoAccount{} }
+func (m *protoAccount) String() string { return proto.CompactTextString(m) }
+func (*protoAccount) ProtoMessage()    {}
+func (*protoAccount) Descriptor() ([]byte, []int) {
+	return protoTestDescriptor, []int{0}
+}
+func (*protoAccount) XXX_OneofWrappers() []interface{} {
+	return []interface{}{(*protoAccountEmail)(nil), (*protoAccountPhone)(nil)}
+}
+
+type isProtoAccountContact interface {
+	isProtoAccountContact()
+}
+
+type protoAccountEmail struct {
+	Email string `protobuf:"bytes,5,opt,name=email,proto3,oneof"`
+}
+
+type protoAccountPhone struct {
+	Phone string `protobuf:"bytes,6,opt,name=phone,proto3,oneof"`
+}
+
+func (*protoAccountEmail) isProtoAccountContact() {}
+func (*protoAccountPhone) isProtoAccountContact() {}
+
+type protoAddress struct {
+	City                 string   `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *protoAddress) Reset()         { *m = protoAddress{} }
+func (m *protoAddress) String() string { return proto.CompactTextString(m) }
+func (*protoAddress) ProtoMessage()    {}
+func (*protoAddress) Descriptor() ([]byte, []int) {
+	return protoTestDescriptor, []int{1}
+}
+
+var protoTestDescriptor = func() []byte {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	address := field("address", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".bucket.test.Address")
+	address.Options = &descriptorpb.FieldOptions{}
+	if err := proto.SetExtension(address.Options, E_Referenced, proto.String("proto_account_address")); err != nil {
+		panic(err)
+	}
+
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("bucket_test.proto"),
+		Package:    proto.String("bucket.test"),
+		Dependency: []string{"bucket.proto", "google/protobuf/timestamp.proto", "google/protobuf/duration.proto", "google/protobuf/wrappers.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Account"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("display_name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("created", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("session", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Duration"),
+					field("nickname", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.StringValue"),
+					field("email", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("phone", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					address,
+				},
+			},
+			{
+				Name:  proto.String("Address"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+			},
+		},
+	}
+	fd.MessageType[0].Field[4].OneofIndex = proto.Int32(0)
+	fd.MessageType[0].Field[5].OneofIndex = proto.Int32(0)
+	fd.MessageType[0].OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("contact")}}
+
+	data, err := proto.Marshal(fd)
+	if err != nil {
+		panic(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}()
+
+func generateProtoAccount() *protoAccount {
+	created, _ := ptypes.TimestampProto(time.Date(2019, 11, 2, 10, 30, 0, 0, time.UTC))
+	return &protoAccount{
+		DisplayName: "Jane Doe",
+		Created:     created,
+		Session:     ptypes.DurationProto(90 * time.Minute),
+		Nickname:    &wrappers.StringValue{Value: "jd"},
+		Contact:     &protoAccountEmail{Email: "jane@example.com"},
+		Address:     &protoAddress{City: "Budapest"},
+	}
+}
+
+func TestReferencedTagProtoOption(t *testing.T) {
+	rt := reflect.TypeOf(protoAccount{})
+	field, _ := rt.FieldByName("Address")
+	tag, ok := referencedTag(rt, field)
+	assert.True(t, ok)
+	assert.Equal(t, "proto_account_address", tag)
+
+	field, _ = rt.FieldByName("DisplayName")
+	_, ok = referencedTag(rt, field)
+	assert.False(t, ok)
+}
+
+func TestProtoInsert(t *testing.T) {
+	account := generateProtoAccount()
+	_, id, err := th.Insert(context.Background(), "proto_account", "", account, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw map[string]interface{}
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("proto_account", id), &raw); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Jane Doe", raw["display_name"])
+	assert.Equal(t, "2019-11-02T10:30:00Z", raw["created"])
+	assert.Equal(t, "5400s", raw["session"])
+	assert.Equal(t, "jd", raw["nickname"])
+	assert.Equal(t, "jane@example.com", raw["email"])
+	assert.NotContains(t, raw, "address")
+
+	var address map[string]interface{}
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("proto_account_address", id), &address); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Budapest", address["city"])
+}
+
+func TestProtoGet(t *testing.T) {
+	account := generateProtoAccount()
+	account.Contact = &protoAccountPhone{Phone: "+36 1 234 5678"}
+	_, id, err := th.Insert(context.Background(), "proto_account", "", account, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result protoAccount
+	if err := th.Get(context.Background(), "proto_account", id, &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, proto.Equal(account, &result), "expected %v, got %v", account, &result)
+}
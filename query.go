@@ -0,0 +1,45 @@
+package bucket
+
+import (
+	"context"
+
+	"github.com/couchbase/gocb"
+)
+
+// errPreparedNotFound is the code of the query service when it doesn't know the prepared
+// statement, like after its restart, the gocb re-prepares only on the invalidated plans
+const errPreparedNotFound = 4040
+
+// query executes the N1QL statement prepared or ad-hoc by the context and the configuration,
+// the prepared statements are cached by their text and re-prepared when their plan is invalid
+func (h *Handler) query(ctx context.Context, statement string, params interface{}) (gocb.QueryResults, error) {
+	prepared := h.prepared(ctx)
+	q := gocb.NewN1qlQuery(statement).AdHoc(!prepared)
+	consistentQuery(ctx, q)
+	rows, err := h.state.bucket.ExecuteN1qlQuery(q, params)
+	if err != nil && prepared && queryErrorCode(err) == errPreparedNotFound {
+		h.state.bucket.InvalidateQueryCache()
+		return h.state.bucket.ExecuteN1qlQuery(q, params)
+	}
+	return rows, err
+}
+
+// InvalidateQueryCache drops the cached prepared statements, they are prepared again by their next use
+func (h *Handler) InvalidateQueryCache() {
+	h.state.bucket.InvalidateQueryCache()
+}
+
+func (h *Handler) prepared(ctx context.Context) bool {
+	if prepared, ok := preparedFromContext(ctx); ok {
+		return prepared
+	}
+	return h.state.configuration.PreparedQueries
+}
+
+// queryErrorCode returns the code of the query service's error, 0 for the other errors
+func queryErrorCode(err error) uint32 {
+	if e, ok := err.(interface{ Code() uint32 }); ok {
+		return e.Code()
+	}
+	return 0
+}
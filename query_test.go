@@ -0,0 +1,45 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testQueryError uint32
+
+func (e testQueryError) Error() string { return "query error" }
+func (e testQueryError) Code() uint32  { return uint32(e) }
+
+func TestPrepared(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, th.state.configuration.PreparedQueries, th.prepared(ctx))
+	assert.True(t, th.prepared(WithPrepared(ctx, true)))
+	assert.False(t, th.prepared(WithPrepared(ctx, false)))
+}
+
+func TestQueryErrorCode(t *testing.T) {
+	assert.Equal(t, uint32(errPreparedNotFound), queryErrorCode(testQueryError(errPreparedNotFound)))
+	assert.Equal(t, uint32(0), queryErrorCode(errors.New("other")))
+}
+
+func TestFindPrepared(t *testing.T) {
+	ctx := WithPrepared(context.Background(), true)
+	filter := Filter{Where: []Predicate{{Field: "Status", Op: Eq, Value: "processed"}}, Limit: 2}
+
+	var first, second []webshop
+	if err := th.Find(ctx, "webshop", filter, &first); err != nil {
+		t.Fatal(err)
+	}
+	th.InvalidateQueryCache()
+	filter.Offset = 2
+	if err := th.Find(ctx, "webshop", filter, &second); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, first, 2)
+	assert.Len(t, second, 2)
+	assert.NotEqual(t, first[0].Token, second[0].Token)
+}
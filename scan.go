@@ -0,0 +1,148 @@
+package bucket
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// defaultScanBatchSize is the number of root documents queried at once without ScanBatchSize in the configuration
+const defaultScanBatchSize = 100
+
+// Scanner streams the trees of every root document of a type,
+// the documents are read by batches ordered by their keys
+type Scanner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	h      *Handler
+	typ    string
+
+	rt    reflect.Type
+	trees chan reflect.Value
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// Scan returns a Scanner of the trees of the type, the scan starts by the first Next
+// and stops on the cancellation of the context, it should be closed by the Close
+func (h *Handler) Scan(ctx context.Context, typ string) *Scanner {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Scanner{
+		ctx:    ctx,
+		cancel: cancel,
+		h:      h,
+		typ:    typ,
+	}
+}
+
+// Next loads the next tree into the ptr, it returns false at the end of the scan or on error,
+// the ptr must be a pointer to a struct with the same type at every call
+func (s *Scanner) Next(ptr interface{}) bool {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		s.setErr(ErrInputStructPointer)
+		return false
+	}
+
+	// the scan starts under the lock, so the Close sees its channel
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false
+	}
+	if s.trees == nil {
+		s.rt = rv.Elem().Type()
+		s.trees = make(chan reflect.Value, s.h.scanBatchSize())
+		go s.run()
+	} else if rv.Elem().Type() != s.rt {
+		s.mu.Unlock()
+		s.setErr(ErrScanType)
+		return false
+	}
+	trees := s.trees
+	s.mu.Unlock()
+
+	tree, ok := <-trees
+	if !ok {
+		return false
+	}
+	rv.Elem().Set(tree.Elem())
+	return true
+}
+
+// Err returns the error stopped the scan
+func (s *Scanner) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the scan and waits for its loaders, it can be called while another goroutine is in the Next
+func (s *Scanner) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	trees := s.trees
+	s.mu.Unlock()
+
+	s.cancel()
+	if trees != nil {
+		for range trees {
+		}
+	}
+	return nil
+}
+
+// run queries the batches of the keys after the last key of the previous one
+// and sends their trees to the Next until the end of the type
+func (s *Scanner) run() {
+	defer close(s.trees)
+
+	filter := Filter{Limit: s.h.scanBatchSize()}
+	for {
+		rows, next, err := s.h.findRows(s.ctx, s.typ, s.rt, filter)
+		if err != nil {
+			s.setErr(err)
+			return
+		}
+		trees, err := s.h.loadTrees(s.ctx, s.typ, s.rt, rows)
+		if err != nil {
+			s.setErr(err)
+			return
+		}
+		for _, tree := range trees {
+			// it could be removed since the query
+			if !tree.IsValid() {
+				continue
+			}
+			select {
+			case s.trees <- tree:
+			case <-s.ctx.Done():
+				s.setErr(s.ctx.Err())
+				return
+			}
+		}
+
+		if next == "" {
+			return
+		}
+		filter.After = next
+	}
+}
+
+// setErr keeps the first error, the errors caused by the Close are ignored
+func (s *Scanner) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil && !s.closed {
+		s.err = err
+	}
+}
+
+func (h *Handler) scanBatchSize() int {
+	if h.state.configuration.ScanBatchSize > 0 {
+		return h.state.configuration.ScanBatchSize
+	}
+	return defaultScanBatchSize
+}
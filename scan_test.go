@@ -0,0 +1,76 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	s := th.Scan(context.Background(), "webshop")
+	defer s.Close()
+
+	var n int
+	var previous string
+	var order webshop
+	for s.Next(&order) {
+		assert.NotNil(t, order.Product)
+		assert.NotEqual(t, previous, order.Token)
+		previous = order.Token
+		n++
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n < 1 {
+		t.Errorf("scan should return at least 1 tree, instead of %d", n)
+	}
+}
+
+func TestScanClose(t *testing.T) {
+	s := th.Scan(context.Background(), "webshop")
+
+	var order webshop
+	assert.True(t, s.Next(&order))
+	assert.Nil(t, s.Close())
+	assert.False(t, s.Next(&order))
+	assert.Nil(t, s.Err())
+}
+
+func TestScanCloseWhileNext(t *testing.T) {
+	s := th.Scan(context.Background(), "webshop")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var order webshop
+		for s.Next(&order) {
+		}
+	}()
+	assert.Nil(t, s.Close())
+	<-done
+	assert.Nil(t, s.Err())
+}
+
+func TestScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := th.Scan(ctx, "webshop")
+	defer s.Close()
+
+	var order webshop
+	assert.True(t, s.Next(&order))
+	cancel()
+	for s.Next(&order) {
+	}
+	assert.Equal(t, context.Canceled, s.Err())
+}
+
+func TestScanType(t *testing.T) {
+	s := th.Scan(context.Background(), "webshop")
+	defer s.Close()
+
+	assert.True(t, s.Next(&webshop{}))
+	assert.False(t, s.Next(&product{}))
+	assert.Equal(t, ErrScanType, s.Err())
+}
@@ -0,0 +1,116 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/couchbase/gocb"
+)
+
+// SearchResult is the metadata of the SearchInto's hits,
+// the Scores are in the order of the container's trees
+type SearchResult struct {
+	Scores []float64
+	Facets map[string]gocb.SearchResultFacet
+}
+
+// SearchInto runs a SearchQuery, CompoundQueries or RangeQuery with the facets and loads the trees
+// of the hits into the container, the container should be *[]T or *[]*T type, the hits should be
+// root documents of the type of T, the ones removed since the indexing are skipped
+func (h *Handler) SearchInto(ctx context.Context, index string, q interface{}, container interface{}, facets ...FacetDef) (*SearchResult, error) {
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, ErrInvalidSearchContainer
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, ErrInvalidSearchContainer
+	}
+
+	var limit, offset int
+	switch s := q.(type) {
+	case *SearchQuery:
+		limit, offset = s.Limit, s.Offset
+	case *CompoundQueries:
+		limit, offset = s.Limit, s.Offset
+	case *RangeQuery:
+		limit, offset = s.Limit, s.Offset
+	default:
+		return nil, ErrInvalidSearchQuery
+	}
+	if err := q.(interface{ setup() error }).setup(); err != nil {
+		return nil, err
+	}
+	if index == "" {
+		return nil, ErrEmptyIndex
+	}
+
+	query := gocb.NewSearchQuery(index, q).Limit(limit).Skip(offset)
+	h.addFacets(ctx, query, facets)
+	status, hits, facetResult, err := h.doSearch(ctx, query)
+	if status.Errors != nil && !reflect.ValueOf(status.Errors).IsNil() {
+		return nil, fmt.Errorf("%+v", status.Errors)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	trees, err := h.loadHits(ctx, structType, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SearchResult{Facets: facetResult}
+	result := reflect.MakeSlice(slice.Type(), 0, len(trees))
+	for i, tree := range trees {
+		// it could be removed since the indexing
+		if !tree.IsValid() {
+			continue
+		}
+		if elemType.Kind() == reflect.Ptr {
+			result = reflect.Append(result, tree)
+		} else {
+			result = reflect.Append(result, tree.Elem())
+		}
+		res.Scores = append(res.Scores, hits[i].Score)
+	}
+	slice.Set(result)
+
+	return res, nil
+}
+
+// loadHits loads the trees of the hits grouped by the types of their keys, the trees
+// keep the order of the hits and the unknown or removed ones are invalid values
+func (h *Handler) loadHits(ctx context.Context, rt reflect.Type, hits []gocb.SearchResultHit) ([]reflect.Value, error) {
+	var (
+		trees   = make([]reflect.Value, len(hits))
+		rows    = make(map[string][]findRow)
+		indexes = make(map[string][]int)
+	)
+	for i, hit := range hits {
+		typ, ok := h.state.typeOfKey(hit.Id)
+		if !ok {
+			continue
+		}
+		rows[typ] = append(rows[typ], findRow{Key: hit.Id})
+		indexes[typ] = append(indexes[typ], i)
+	}
+
+	for typ := range rows {
+		loaded, err := h.loadTrees(ctx, typ, rt, rows[typ])
+		if err != nil {
+			return nil, err
+		}
+		for j, tree := range loaded {
+			trees[indexes[typ][j]] = tree
+		}
+	}
+
+	return trees, nil
+}
@@ -0,0 +1,66 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchInto(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		ws := generate()
+		ws.Status = "success"
+		_, _, err := th.Insert(context.Background(), "webshop", xid.New().String(), ws, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitUntilFtsIndexCompleted(context.Background(), "webshop_fts_index")
+
+	var ws []webshop
+	res, err := th.SearchInto(context.Background(), "webshop_fts_index", &SearchQuery{
+		Query: "success",
+		Limit: 5,
+	}, &ws, FacetDef{Name: "status", Type: FacetTerm, Field: "status", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) == 0 {
+		t.Fatal("Empty resultset of the search")
+	}
+	assert.Len(t, res.Scores, len(ws))
+	assert.Contains(t, res.Facets, "status")
+	assert.Equal(t, "success", ws[0].Status)
+	assert.Equal(t, "active", ws[0].Product.Status)
+	assert.Equal(t, "productshop", ws[0].Store.Name)
+
+	var pws []*webshop
+	_, err = th.SearchInto(context.Background(), "webshop_fts_index", &CompoundQueries{
+		Conjunction: []SearchQuery{{Match: "success", Field: "status"}},
+	}, &pws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, pws)
+}
+
+func TestSearchIntoInvalid(t *testing.T) {
+	var ws []webshop
+	_, err := th.SearchInto(context.Background(), "webshop_fts_index", &SearchQuery{Query: "success"}, ws)
+	assert.Equal(t, ErrInvalidSearchContainer, err)
+
+	_, err = th.SearchInto(context.Background(), "webshop_fts_index", SearchQuery{Query: "success"}, &ws)
+	assert.Equal(t, ErrInvalidSearchQuery, err)
+}
+
+func TestTypeOfKey(t *testing.T) {
+	typ, ok := th.state.typeOfKey(th.state.getType("webshop") + xid.New().String())
+	assert.True(t, ok)
+	assert.Equal(t, "webshop", typ)
+
+	_, ok = th.state.typeOfKey("unknown_prefix_" + xid.New().String())
+	assert.False(t, ok)
+}
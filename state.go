@@ -28,7 +28,7 @@ type state struct {
 }
 
 func newState(c *Configuration) (*state, error) {
-	cluster, err := gocb.Connect(c.ConnectionString)
+	cluster, err := gocb.Connect(compressionConnectionString(c))
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +40,7 @@ func newState(c *Configuration) (*state, error) {
 		return nil, err
 	}
 
-	bucket, err := cluster.OpenBucket(c.BucketName, c.BucketPassword)
+	bucket, err := cluster.OpenBucketWithMt(c.BucketName, c.BucketPassword)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +90,23 @@ func (s *state) getType(name string) string {
 	return s.DocumentTypes[name]
 }
 
+// typeOfKey returns the document type by the longest prefix of the key, false for the unknown prefixes
+func (s *state) typeOfKey(key string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	var typ, prefix string
+	for name, p := range s.DocumentTypes {
+		if strings.HasPrefix(key, p) && len(p) > len(prefix) {
+			typ, prefix = name, p
+		}
+	}
+	return typ, prefix != ""
+}
+
+func (s *state) typeOptions(name string) TypeOptions {
+	return s.configuration.Types[name]
+}
+
 func (s *state) fetchDocumentIdentifier(documentKey string) string {
 	elems := strings.Split(documentKey, s.configuration.Separator)
 	if len(elems) > 0 {
@@ -116,11 +133,10 @@ func (s *state) deleteType(name string) error {
 	return ErrDocumentTypeDoesntExists
 }
 
-func (s *state) validate() (bool, error) {
+func (s *state) validate(query func(string) (gocb.QueryResults, error)) (bool, error) {
 	key := "doc_type"
 	queryStr := fmt.Sprintf(`SELECT SPLIT(META().id, "%s")[0] %s FROM %s GROUP BY SPLIT(META().id, "%s")[0];`, s.configuration.Separator, key, s.configuration.BucketName, s.configuration.Separator)
-	query := gocb.NewN1qlQuery(queryStr)
-	rows, err := s.bucket.ExecuteN1qlQuery(query, nil)
+	rows, err := query(queryStr)
 	if err != nil {
 		return false, err
 	}
@@ -42,7 +42,7 @@ func TestValidate(t *testing.T) {
 func TestValidateExpectError(t *testing.T) {
 	_ = th.state.updateState()
 	delete(th.state.DocumentTypes, "webshop")
-	if _, err := th.state.validate(); err == nil {
+	if _, err := th.ValidateState(); err == nil {
 		t.Error("Err should be not nil")
 	}
 	_ = th.state.updateState()
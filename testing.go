@@ -128,6 +128,13 @@ type store struct {
 	Description string `json:"description"`
 }
 
+// testKeyRing returns the keys of the encrypted fields of the test models
+func testKeyRing() *KeyRing {
+	keys := NewKeyRing()
+	keys.Add("webshop_pii", "webshop_pii_1", []byte("0123456789abcdef0123456789abcdef"))
+	return keys
+}
+
 func generate() webshop {
 	addr := gofakeit.Address()
 	name := gofakeit.Name()
@@ -0,0 +1,83 @@
+package bucket
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	tagTTL = "cb_ttl" // ttl tag represents the own expiry of a referenced document as duration or seconds
+
+	// maxRelativeExpiry is the longest expiry couchbase handles as relative, the
+	// longer ones must be given as unix timestamp
+	maxRelativeExpiry = 30 * 24 * 60 * 60
+
+	// expiryXattr is the virtual extended attribute of the expiry of the documents
+	expiryXattr = "$document.exptime"
+)
+
+// TouchPolicy defines what the Touch and GetAndTouch do with the children having own ttl
+type TouchPolicy string
+
+// Available touch policies
+const (
+	// TouchPreserve leaves the expiry of the children with own ttl untouched
+	TouchPreserve TouchPolicy = "preserve"
+	// TouchOverride sets the ttl of the call on every document of the tree
+	TouchOverride TouchPolicy = "override"
+	// TouchRenew renews the expiry of the children with own ttl by their ttl
+	TouchRenew TouchPolicy = "renew"
+)
+
+// fieldTTL returns the ttl of a referenced field from its cb_ttl tag,
+// without the tag the child inherits the ttl of its parent
+func fieldTTL(field reflect.StructField, inherited uint32) (uint32, error) {
+	tag, ok := field.Tag.Lookup(tagTTL)
+	if !ok {
+		return inherited, nil
+	}
+
+	if seconds, err := strconv.ParseUint(tag, 10, 32); err == nil && seconds > 0 {
+		return uint32(seconds), nil
+	}
+	d, err := time.ParseDuration(tag)
+	if err != nil || d < time.Second {
+		return 0, ErrInvalidTTL
+	}
+	return uint32(d / time.Second), nil
+}
+
+// expiry converts the ttl in seconds to the expiry of couchbase
+func expiry(ttl uint32) uint32 {
+	if ttl > maxRelativeExpiry {
+		return uint32(time.Now().Unix()) + ttl
+	}
+	return ttl
+}
+
+// documentExpiry returns the expiry of a document of the tree, it's
+// the document's own ttl if it has one, otherwise the ttl of the write
+func documentExpiry(document map[string]interface{}, ttl uint32) uint32 {
+	if m, ok := document[metaFieldName].(*meta); ok && m.TTL > 0 {
+		return expiry(m.TTL)
+	}
+	return ttl
+}
+
+// touchExpiry returns the expiry set by the Touch and GetAndTouch on a document with
+// the own ttl by the touch policy, false means the document mustn't be touched
+func (h *Handler) touchExpiry(own, ttl uint32) (uint32, bool) {
+	if own == 0 {
+		return ttl, true
+	}
+
+	switch h.state.configuration.TouchPolicy {
+	case TouchOverride:
+		return ttl, true
+	case TouchRenew:
+		return expiry(own), true
+	default:
+		return 0, false
+	}
+}
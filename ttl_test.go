@@ -0,0 +1,138 @@
+package bucket
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+type cardOrder struct {
+	Status string     `json:"status"`
+	Token  *cardToken `json:"token" cb_referenced:"card_token" cb_ttl:"1h"`
+}
+
+type cardToken struct {
+	Value       string           `json:"value"`
+	Fingerprint *cardFingerprint `json:"fingerprint" cb_referenced:"card_fingerprint"`
+}
+
+type cardFingerprint struct {
+	Hash string `json:"hash"`
+}
+
+func TestFieldTTL(t *testing.T) {
+	var tests = []struct {
+		tag      reflect.StructTag
+		expected uint32
+		err      error
+	}{
+		{tag: `cb_ttl:"1h"`, expected: 3600},
+		{tag: `cb_ttl:"90"`, expected: 90},
+		{tag: ``, expected: 42},
+		{tag: `cb_ttl:"soon"`, err: ErrInvalidTTL},
+		{tag: `cb_ttl:"10ms"`, err: ErrInvalidTTL},
+	}
+
+	for _, test := range tests {
+		ttl, err := fieldTTL(reflect.StructField{Tag: test.tag}, 42)
+		assert.Equal(t, test.err, err, string(test.tag))
+		assert.Equal(t, test.expected, ttl, string(test.tag))
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	assert.Equal(t, uint32(3600), expiry(3600))
+	assert.True(t, expiry(maxRelativeExpiry+1) > uint32(time.Now().Unix()))
+}
+
+func TestTouchExpiry(t *testing.T) {
+	h := &Handler{state: &state{configuration: &Configuration{}}}
+	var tests = []struct {
+		policy   TouchPolicy
+		expected uint32
+		touch    bool
+	}{
+		{policy: "", expected: 0, touch: false},
+		{policy: TouchPreserve, expected: 0, touch: false},
+		{policy: TouchOverride, expected: 60, touch: true},
+		{policy: TouchRenew, expected: 3600, touch: true},
+	}
+
+	for _, test := range tests {
+		h.state.configuration.TouchPolicy = test.policy
+		expiry, touch := h.touchExpiry(3600, 60)
+		assert.Equal(t, test.expected, expiry, string(test.policy))
+		assert.Equal(t, test.touch, touch, string(test.policy))
+
+		expiry, touch = h.touchExpiry(0, 60)
+		assert.Equal(t, uint32(60), expiry)
+		assert.True(t, touch)
+	}
+}
+
+func TestGetSubDocumentsTTL(t *testing.T) {
+	order := cardOrder{Status: "paid", Token: &cardToken{Value: "tok", Fingerprint: &cardFingerprint{Hash: "abc"}}}
+	documents, err := th.getSubDocuments("card_order", xid.New().String(), order, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, uint32(120), documentExpiry(documents["card_order"], 120))
+	assert.Equal(t, uint32(3600), documentExpiry(documents["card_token"], 120))
+	assert.Equal(t, uint32(3600), documentExpiry(documents["card_fingerprint"], 120))
+
+	root := documents["card_order"][metaFieldName].(*meta)
+	for _, child := range root.ChildDocuments {
+		assert.Equal(t, uint32(3600), child.TTL)
+	}
+}
+
+func TestInsertWithChildTTL(t *testing.T) {
+	ctx := context.Background()
+	order := cardOrder{Status: "paid", Token: &cardToken{Value: "tok", Fingerprint: &cardFingerprint{Hash: "abc"}}}
+	_, id, err := th.Insert(ctx, "card_order", "", order, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got cardOrder
+	if err := th.GetAndTouch(ctx, "card_order", id, &got, 60); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, order, got)
+
+	if err := th.Touch(ctx, "card_order", id, &got, 60); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type sessionUser struct {
+	Name    string        `json:"name"`
+	Session *sessionToken `json:"session" cb_referenced:"session_token" cb_ttl:"1h"`
+}
+
+type sessionToken struct {
+	Value string `json:"value" cb_unique:"true"`
+}
+
+func TestInsertUniqueWithChildTTL(t *testing.T) {
+	ctx := context.Background()
+	user := sessionUser{Name: "alice", Session: &sessionToken{Value: xid.New().String()}}
+	_, id, err := th.Insert(ctx, "session_user", "", user, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := th.lookupDocumentKey("session_token", "value", user.Session.Value)
+	assert.InDelta(t, keyExpiry(t, th.state.getDocumentKey("session_token", id)), keyExpiry(t, key), 1)
+	assert.NotZero(t, keyExpiry(t, key))
+
+	if err := th.Touch(ctx, "session_user", id, &sessionUser{}, 60); err != nil {
+		t.Fatal(err)
+	}
+	assert.InDelta(t, keyExpiry(t, th.state.getDocumentKey("session_token", id)), keyExpiry(t, key), 1)
+}
@@ -0,0 +1,155 @@
+package bucket
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/couchbase/gocb"
+)
+
+// lookupKeyword separates the lookup documents from the documents of the type
+const lookupKeyword = "unique"
+
+// lookup is the content of a lookup document, it points from
+// the value of a unique field to the tree which holds it
+type lookup struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// lookupDocumentKey returns the key of the lookup document of a unique field's value, for example the
+// lookup of webshop's email is webshop::unique::email::<email>, the keyword keeps the types apart
+func (h *Handler) lookupDocumentKey(typ, field string, value interface{}) string {
+	return h.lookupPrefix(typ) + field + h.state.configuration.Separator + fmt.Sprint(value)
+}
+
+func (h *Handler) lookupPrefix(typ string) string {
+	sep := h.state.configuration.Separator
+	return typ + sep + lookupKeyword + sep
+}
+
+// lookupOwner returns the type of the document holding the unique field of the lookup key from the types of the tree
+func (h *Handler) lookupOwner(key string, typs []string) (string, bool) {
+	for _, typ := range typs {
+		if strings.HasPrefix(key, h.lookupPrefix(typ)) {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// lookupExpiry returns the expiry of the lookup document, it's the expiry of the document holding
+// the unique field, so the lookup of a child with own ttl expires together with the child
+func (h *Handler) lookupExpiry(key string, documents map[string]map[string]interface{}, ttl uint32) uint32 {
+	var typs []string
+	for typ := range documents {
+		typs = append(typs, typ)
+	}
+	if typ, ok := h.lookupOwner(key, typs); ok {
+		return documentExpiry(documents[typ], ttl)
+	}
+	return ttl
+}
+
+// lookupKey returns the key of the lookup document belongs to the field,
+// if the field isn't unique or it has zero value it returns false
+func (h *Handler) lookupKey(typ, field string, rvField reflect.Value, rtField reflect.StructField) (string, bool) {
+	if rtField.Tag.Get(tagUnique) != "true" {
+		return "", false
+	}
+
+	for rvField.Kind() == reflect.Ptr {
+		if rvField.IsNil() {
+			return "", false
+		}
+		rvField = rvField.Elem()
+	}
+	if isZero(rvField) {
+		return "", false
+	}
+
+	return h.lookupDocumentKey(typ, field, rvField.Interface()), true
+}
+
+// documentLookups returns the lookup keys of the whole tree
+// collected by the getSubDocuments into the root's meta
+func documentLookups(documents map[string]map[string]interface{}, typ string) []string {
+	if m, ok := documents[typ][metaFieldName].(*meta); ok {
+		return m.Lookups
+	}
+	return nil
+}
+
+// reserveLookups creates the lookup documents of the tree with the expiry of their documents and returns the keys of
+// the newly created ones, if one of them already used by another tree it returns ErrUniqueConstraintViolation
+func (h *Handler) reserveLookups(ctx context.Context, typ, id string, keys []string, documents map[string]map[string]interface{}, ttl uint32) ([]string, error) {
+	var reserved []string
+	var l = lookup{ID: id, Type: typ}
+	for _, key := range keys {
+		expiry := h.lookupExpiry(key, documents, ttl)
+		_, token, err := h.state.bucket.InsertMt(key, l, expiry)
+		if err == nil {
+			recordMutation(ctx, token)
+			reserved = append(reserved, key)
+			continue
+		}
+		if err == gocb.ErrKeyExists {
+			err = h.takeOverLookup(ctx, key, l, expiry)
+		}
+		if err != nil {
+			h.releaseLookups(ctx, reserved)
+			return nil, err
+		}
+	}
+
+	return reserved, nil
+}
+
+// takeOverLookup refreshes the lookup document if it's owned by
+// the same tree, otherwise returns ErrUniqueConstraintViolation
+func (h *Handler) takeOverLookup(ctx context.Context, key string, l lookup, ttl uint32) error {
+	var current lookup
+	cas, err := h.state.bucket.Get(key, &current)
+	if err != nil {
+		return err
+	}
+	if current != l {
+		return ErrUniqueConstraintViolation
+	}
+
+	_, token, err := h.state.bucket.ReplaceMt(key, l, cas, ttl)
+	if err != nil {
+		if err == gocb.ErrKeyExists {
+			return ErrUniqueConstraintViolation
+		}
+		return err
+	}
+	recordMutation(ctx, token)
+	return nil
+}
+
+// releaseLookups removes the lookup documents, errors are ignored
+// because the lookup documents are already invalid at this point
+func (h *Handler) releaseLookups(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		_ = h.remove(ctx, key)
+	}
+}
+
+// staleLookups returns the previous lookup keys not used by the current tree
+func staleLookups(previous, current []string) []string {
+	var used = make(map[string]bool)
+	for _, key := range current {
+		used[key] = true
+	}
+
+	var stale []string
+	for _, key := range previous {
+		if !used[key] {
+			stale = append(stale, key)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,206 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit"
+	"github.com/couchbase/gocb"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+type uniqueCustomer struct {
+	Email   string         `json:"email" cb_unique:"true"`
+	Name    string         `json:"name"`
+	Account *uniqueAccount `json:"account" cb_referenced:"unique_account"`
+}
+
+type uniqueAccount struct {
+	Username string `json:"username" cb_unique:"true"`
+}
+
+func generateUniqueCustomer() uniqueCustomer {
+	return uniqueCustomer{
+		Email: xid.New().String() + gofakeit.Email(),
+		Name:  gofakeit.Name(),
+		Account: &uniqueAccount{
+			Username: xid.New().String(),
+		},
+	}
+}
+
+func TestInsertUnique(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	_, id, err := th.Insert(ctx, "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := th.getMeta("unique_customer", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, m.Lookups, 2)
+
+	var l lookup
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("unique_customer_email", c.Email), &l); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, lookup{ID: id, Type: "unique_customer"}, l)
+}
+
+func TestInsertUniqueViolationExpectError(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	if _, _, err := th.Insert(ctx, "unique_customer", "", c, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	d := generateUniqueCustomer()
+	d.Email = c.Email
+	if _, _, err := th.Insert(ctx, "unique_customer", "", d, 0); err != ErrUniqueConstraintViolation {
+		t.Errorf("error should be %s instead of %s", ErrUniqueConstraintViolation, err)
+	}
+
+	// the lookup of the child reserved before the violation must be released
+	var l lookup
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("unique_account_username", d.Account.Username), &l); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+	}
+}
+
+func TestUpsertUniqueMovesLookup(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	_, id, err := th.Insert(ctx, "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousEmail := c.Email
+	c.Email = xid.New().String() + gofakeit.Email()
+	if _, _, err := th.Upsert(ctx, "unique_customer", id, c, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var l lookup
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("unique_customer_email", c.Email), &l); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, l.ID)
+	if _, err := th.state.bucket.Get(th.state.getDocumentKey("unique_customer_email", previousEmail), &l); err != gocb.ErrKeyNotFound {
+		t.Errorf("error should be %s instead of %s", gocb.ErrKeyNotFound, err)
+	}
+
+	// the same values can be upserted again by the owner
+	if _, _, err := th.Upsert(ctx, "unique_customer", id, c, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertUniqueViolationExpectError(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	if _, _, err := th.Insert(ctx, "unique_customer", "", c, 0); err != nil {
+		t.Fatal(err)
+	}
+	d := generateUniqueCustomer()
+	_, id, err := th.Insert(ctx, "unique_customer", "", d, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Email = c.Email
+	if _, _, err := th.Upsert(ctx, "unique_customer", id, d, 0); err != ErrUniqueConstraintViolation {
+		t.Errorf("error should be %s instead of %s", ErrUniqueConstraintViolation, err)
+	}
+}
+
+func TestRemoveUniqueReleasesLookups(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	_, id, err := th.Insert(ctx, "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Remove(ctx, "unique_customer", id, &uniqueCustomer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := th.Insert(ctx, "unique_customer", "", c, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStaleLookups(t *testing.T) {
+	stale := staleLookups([]string{"a", "b", "c"}, []string{"b", "d"})
+	assert.Equal(t, []string{"a", "c"}, stale)
+}
+
+func TestGetBy(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	if _, _, err := th.Insert(ctx, "unique_customer", "", c, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var got uniqueCustomer
+	if err := th.GetBy(ctx, "unique_customer", "email", c.Email, &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c, got)
+}
+
+func TestGetByNotFoundExpectError(t *testing.T) {
+	var got uniqueCustomer
+	err := th.GetBy(context.Background(), "unique_customer", "email", xid.New().String(), &got)
+	if err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+}
+
+func TestGetByRemovedTreeExpectError(t *testing.T) {
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	_, id, err := th.Insert(ctx, "unique_customer", "", c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lookup points to a removed tree
+	var got uniqueCustomer
+	key := th.lookupDocumentKey("unique_customer", "email", c.Email)
+	if _, err := th.state.bucket.Remove(th.state.getDocumentKey("unique_customer", id), 0); err != nil {
+		t.Fatal(err)
+	}
+	err = th.GetBy(ctx, "unique_customer", "email", c.Email, &got)
+	if err != ErrNotFound {
+		t.Errorf("error should be %s instead of %s", ErrNotFound, err)
+	}
+	_, _ = th.state.bucket.Remove(key, 0)
+}
+
+func TestInsertUniqueOtherType(t *testing.T) {
+	type uniqueOther struct {
+		CustomerEmail string `json:"customer_email" cb_unique:"true"`
+	}
+	ctx := context.Background()
+	c := generateUniqueCustomer()
+	if _, _, err := th.Insert(ctx, "unique_customer", "", c, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same value of a field with a colliding type and field name
+	if _, _, err := th.Insert(ctx, "unique", "", uniqueOther{CustomerEmail: c.Email}, 0); err != nil {
+		t.Errorf("error should be nil instead of %s", err)
+	}
+}
+
+func TestGetByInvalidInput(t *testing.T) {
+	err := th.GetBy(context.Background(), "unique_customer", "email", xid.New().String(), uniqueCustomer{})
+	if err != ErrInputStructPointer {
+		t.Errorf("error should be %s instead of %s", ErrInputStructPointer, err)
+	}
+}
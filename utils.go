@@ -56,6 +56,13 @@ func defaultHandler() *Handler {
 		BucketName:     bucketName,
 		BucketPassword: "",
 		Separator:      "::",
+		Types: map[string]TypeOptions{
+			"soft_webshop":        {SoftDelete: true},
+			"audit_webshop":       {History: true},
+			"msgpack_webshop":     {Codec: CodecMsgpack},
+			"proto_codec_account": {Codec: CodecProtobuf},
+		},
+		KeyProvider: testKeyRing(),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -87,7 +94,7 @@ func getDocumentTypes(value interface{}) ([]string, error) {
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
 		structField := val.Field(i)
-		if val, ok := typeField.Tag.Lookup(tagReferenced); ok {
+		if val, ok := referencedTag(typ, typeField); ok {
 			typs = append(typs, val)
 			if structField.IsNil() && structField.CanSet() {
 				structField.Set(reflect.New(structField.Type().Elem()))
@@ -110,7 +117,7 @@ func getStructAddressableSubfields(value reflect.Value) map[string]interface{} {
 	var result = make(map[string]interface{})
 	typ := value.Type()
 	for i := 0; i < typ.NumField(); i++ {
-		if tag, ok := typ.Field(i).Tag.Lookup(tagReferenced); ok && tag != "" {
+		if tag, ok := referencedTag(typ, typ.Field(i)); ok && tag != "" {
 			result[tag] = value.Field(i).Addr().Interface()
 		}
 	}
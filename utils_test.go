@@ -7,6 +7,18 @@ import (
 	"github.com/rs/xid"
 )
 
+// testHandler returns a handler with the configuration of the th and the types,
+// so the tests can use their own type options without changing the shared one
+func testHandler(t *testing.T, types map[string]TypeOptions) *Handler {
+	conf := *th.state.configuration
+	conf.Types = types
+	h, err := New(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
 func TestGetDocumentTypesWithPointer(t *testing.T) {
 	typs, err := getDocumentTypes(&webshop{})
 	if err != nil {
@@ -0,0 +1,185 @@
+package bucket
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	tagRequired = "cb_required" // required tag represents fields must not be zero
+	tagMin      = "cb_min"      // min tag represents the minimum of numbers or the minimum length of strings, slices and maps
+	tagMax      = "cb_max"      // max tag represents the maximum of numbers or the maximum length of strings, slices and maps
+	tagPattern  = "cb_pattern"  // pattern tag represents the regular expression of strings
+	tagEnum     = "cb_enum"     // enum tag represents the comma separated list of the allowed values
+)
+
+var patterns sync.Map
+
+// FieldError describes a field failed on a validation rule
+type FieldError struct {
+	Path    string
+	Rule    string
+	Message string
+}
+
+// ValidationError returned by the writes when fields of the tree are invalid,
+// it contains every failing field of the tree
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f.Path, f.Message))
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+// validate evaluates the validation tags of the whole tree
+func validate(q interface{}) error {
+	var e = &ValidationError{}
+	validateStruct(reflect.ValueOf(q), "", e)
+	if len(e.Fields) > 0 {
+		return e
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, path string, e *ValidationError) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		rtField := rt.Field(i)
+		if rtField.PkgPath != "" {
+			continue
+		}
+		fieldPath := rtField.Name
+		if path != "" {
+			fieldPath = path + "." + rtField.Name
+		}
+		validateField(rv.Field(i), rtField, fieldPath, e)
+		validateStruct(rv.Field(i), fieldPath, e)
+	}
+}
+
+func validateField(rv reflect.Value, rtField reflect.StructField, path string, e *ValidationError) {
+	if rtField.Tag.Get(tagRequired) == "true" && isZero(rv) {
+		e.Fields = append(e.Fields, FieldError{Path: path, Rule: tagRequired, Message: "required"})
+		return
+	}
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	if min, ok := rtField.Tag.Lookup(tagMin); ok {
+		if fe, failed := validateBound(rv, min, path, tagMin); failed {
+			e.Fields = append(e.Fields, fe)
+		}
+	}
+	if max, ok := rtField.Tag.Lookup(tagMax); ok {
+		if fe, failed := validateBound(rv, max, path, tagMax); failed {
+			e.Fields = append(e.Fields, fe)
+		}
+	}
+	if isZero(rv) {
+		return
+	}
+	if pattern, ok := rtField.Tag.Lookup(tagPattern); ok {
+		if fe, failed := validatePattern(rv, pattern, path); failed {
+			e.Fields = append(e.Fields, fe)
+		}
+	}
+	if enum, ok := rtField.Tag.Lookup(tagEnum); ok {
+		if fe, failed := validateEnum(rv, enum, path); failed {
+			e.Fields = append(e.Fields, fe)
+		}
+	}
+}
+
+func validateBound(rv reflect.Value, bound, path, rule string) (FieldError, bool) {
+	limit, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return FieldError{Path: path, Rule: rule, Message: fmt.Sprintf("invalid %s: %s", rule, bound)}, true
+	}
+
+	var value float64
+	var subject = "value"
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = rv.Float()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		value = float64(rv.Len())
+		subject = "length"
+	default:
+		return FieldError{}, false
+	}
+
+	if rule == tagMin && value < limit {
+		return FieldError{Path: path, Rule: rule, Message: fmt.Sprintf("%s must be at least %s", subject, bound)}, true
+	}
+	if rule == tagMax && value > limit {
+		return FieldError{Path: path, Rule: rule, Message: fmt.Sprintf("%s must be at most %s", subject, bound)}, true
+	}
+	return FieldError{}, false
+}
+
+func validatePattern(rv reflect.Value, pattern, path string) (FieldError, bool) {
+	if rv.Kind() != reflect.String {
+		return FieldError{}, false
+	}
+
+	var re *regexp.Regexp
+	if v, ok := patterns.Load(pattern); ok {
+		re = v.(*regexp.Regexp)
+	} else {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return FieldError{Path: path, Rule: tagPattern, Message: fmt.Sprintf("invalid pattern: %s", pattern)}, true
+		}
+		patterns.Store(pattern, re)
+	}
+
+	if !re.MatchString(rv.String()) {
+		return FieldError{Path: path, Rule: tagPattern, Message: fmt.Sprintf("must match %s", pattern)}, true
+	}
+	return FieldError{}, false
+}
+
+func validateEnum(rv reflect.Value, enum, path string) (FieldError, bool) {
+	value := fmt.Sprint(rv.Interface())
+	for _, allowed := range strings.Split(enum, ",") {
+		if strings.TrimSpace(allowed) == value {
+			return FieldError{}, false
+		}
+	}
+	return FieldError{Path: path, Rule: tagEnum, Message: fmt.Sprintf("must be one of %s", enum)}, true
+}
+
+func isZero(rv reflect.Value) bool {
+	if !rv.IsValid() {
+		return true
+	}
+	return reflect.DeepEqual(rv.Interface(), reflect.Zero(rv.Type()).Interface())
+}
@@ -0,0 +1,86 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedOrder struct {
+	Email    string            `json:"email" cb_required:"true" cb_pattern:"^[^@]+@[^@]+$"`
+	Status   string            `json:"status" cb_enum:"new,paid,shipped"`
+	Total    int               `json:"total" cb_min:"1" cb_max:"10000"`
+	Items    []string          `json:"items" cb_min:"1"`
+	Shipping *validatedAddress `json:"shipping" cb_referenced:"validated_address" cb_required:"true"`
+}
+
+type validatedAddress struct {
+	Country string `json:"country" cb_required:"true" cb_max:"2"`
+}
+
+func TestValidateTree(t *testing.T) {
+	o := validatedOrder{
+		Email:    "alice@example.com",
+		Status:   "paid",
+		Total:    100,
+		Items:    []string{"book"},
+		Shipping: &validatedAddress{Country: "HU"},
+	}
+	assert.NoError(t, validate(o))
+	assert.NoError(t, validate(&o))
+}
+
+func TestValidateTreeExpectError(t *testing.T) {
+	o := validatedOrder{
+		Email:    "invalid",
+		Status:   "lost",
+		Total:    0,
+		Shipping: &validatedAddress{Country: "Hungary"},
+	}
+	err := validate(o)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("error should be ValidationError, instead of %T", err)
+	}
+
+	var paths = make(map[string]string)
+	for _, f := range verr.Fields {
+		paths[f.Path] = f.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"Email":            tagPattern,
+		"Status":           tagEnum,
+		"Total":            tagMin,
+		"Items":            tagMin,
+		"Shipping.Country": tagMax,
+	}, paths)
+}
+
+func TestValidateTreeRequired(t *testing.T) {
+	err := validate(validatedOrder{Total: 1, Items: []string{"book"}})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("error should be ValidationError, instead of %T", err)
+	}
+	assert.Equal(t, []FieldError{
+		{Path: "Email", Rule: tagRequired, Message: "required"},
+		{Path: "Shipping", Rule: tagRequired, Message: "required"},
+	}, verr.Fields)
+}
+
+func TestInsertValidationExpectError(t *testing.T) {
+	_, id, err := th.Insert(context.Background(), "validated_order", "", validatedOrder{}, 0)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("error should be ValidationError, instead of %v", err)
+	}
+	if _, err := th.getMeta("validated_order", id); err == nil {
+		t.Error("invalid document shouldn't be stored")
+	}
+}
+
+func TestUpsertValidationExpectError(t *testing.T) {
+	if _, _, err := th.Upsert(context.Background(), "validated_order", "", validatedOrder{}, 0); err == nil {
+		t.Error("error should be ValidationError instead of nil")
+	}
+}
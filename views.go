@@ -0,0 +1,222 @@
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/couchbase/gocb"
+)
+
+const (
+	tagView = "cb_view" // view tag generates a view of the type emitting the field by the given name
+
+	// devDesignPrefix is the prefix of the development design documents
+	devDesignPrefix = "dev_"
+
+	// viewCountReduce is the built-in reduce of the generated views
+	viewCountReduce = "_count"
+)
+
+// View is a map/reduce view of a document type, without Map the view emits the Field,
+// a json path of the type's root documents, the Reduce can be a built-in like _count
+type View struct {
+	Name   string
+	Field  string
+	Map    string
+	Reduce string
+}
+
+// ViewQuery describes the rows of the QueryView, the Development
+// queries the views pushed by the SetViews before the PublishViews
+type ViewQuery struct {
+	Key          interface{}
+	Keys         []interface{}
+	StartKey     interface{}
+	EndKey       interface{}
+	InclusiveEnd bool
+	Descending   bool
+	Limit        uint
+	Skip         uint
+	Reduce       bool
+	Group        bool
+	GroupLevel   uint
+	Development  bool
+}
+
+// ViewsOf returns the views generated from the cb_view tags of the root's fields,
+// the views emit the field and count the documents by the _count reduce
+func ViewsOf(v interface{}) ([]View, error) {
+	rt := reflect.TypeOf(v)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, ErrFirstParameterNotStruct
+	}
+
+	var views []View
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, ok := field.Tag.Lookup(tagView)
+		if !ok {
+			continue
+		}
+		if _, ok := field.Tag.Lookup(tagEncrypted); ok {
+			return nil, ErrEncryptedField
+		}
+		if _, ok := referencedTag(rt, field); ok {
+			return nil, ErrReferencedField
+		}
+		j := removeOmitempty(field.Tag.Get(tagJSON))
+		if j == "" || j == "-" {
+			return nil, ErrUnknownField
+		}
+		views = append(views, View{Name: name, Field: j, Reduce: viewCountReduce})
+	}
+
+	return views, nil
+}
+
+// SetViews pushes the views as the development design document of the type,
+// it replaces the previous views, the PublishViews promotes them to production
+func (h *Handler) SetViews(ctx context.Context, typ string, views []View) error {
+	ddoc := &gocb.DesignDocument{
+		Name:  devDesignPrefix + typ,
+		Views: make(map[string]gocb.View),
+	}
+	for _, v := range views {
+		if v.Name == "" || (v.Map == "" && v.Field == "") {
+			return ErrInvalidView
+		}
+		m := v.Map
+		if m == "" {
+			m = h.viewMap(typ, v.Field)
+		}
+		ddoc.Views[v.Name] = gocb.View{Map: m, Reduce: v.Reduce}
+	}
+
+	return h.GetManager(ctx).UpsertDesignDocument(ddoc)
+}
+
+// PublishViews promotes the development design document of the type to production
+func (h *Handler) PublishViews(ctx context.Context, typ string) error {
+	manager := h.GetManager(ctx)
+	ddoc, err := manager.GetDesignDocument(devDesignPrefix + typ)
+	if err != nil {
+		return err
+	}
+	ddoc.Name = typ
+
+	return manager.UpsertDesignDocument(ddoc)
+}
+
+// RemoveViews removes the production and development design documents of the type,
+// the missing ones are skipped but the other errors of reading them are returned
+func (h *Handler) RemoveViews(ctx context.Context, typ string) error {
+	manager := h.GetManager(ctx)
+	for _, name := range []string{typ, devDesignPrefix + typ} {
+		if _, err := manager.GetDesignDocument(name); err != nil {
+			if designDocumentNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if err := manager.RemoveDesignDocument(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// designDocumentNotFound reports whether the error of the GetDesignDocument means the missing design
+// document, gocb returns only the message of the response with its status code in it for that
+func designDocumentNotFound(err error) bool {
+	return strings.HasSuffix(err.Error(), "Status Code: 404")
+}
+
+// QueryView runs a view of the type and decodes its rows into the container, the container
+// should be *[]T where T has the json fields id, key and value of the rows, the reduced rows
+// have only key and value, with the AtPlus or RequestPlus consistency the index is updated first
+func (h *Handler) QueryView(ctx context.Context, typ, view string, q ViewQuery, container interface{}) error {
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return ErrInvalidViewContainer
+	}
+
+	vq := gocb.NewViewQuery(typ, view).
+		Development(q.Development).
+		Reduce(q.Reduce || q.Group || q.GroupLevel > 0)
+	if q.Key != nil {
+		vq.Key(q.Key)
+	}
+	if len(q.Keys) > 0 {
+		vq.Keys(q.Keys)
+	}
+	if q.StartKey != nil || q.EndKey != nil {
+		vq.Range(q.StartKey, q.EndKey, q.InclusiveEnd)
+	}
+	if q.Descending {
+		vq.Order(gocb.Descending)
+	}
+	if q.Limit > 0 {
+		vq.Limit(q.Limit)
+	}
+	if q.Skip > 0 {
+		vq.Skip(q.Skip)
+	}
+	if q.Group {
+		vq.Group(true)
+	}
+	if q.GroupLevel > 0 {
+		vq.GroupLevel(q.GroupLevel)
+	}
+	if scanConsistency(ctx) != NotBounded {
+		vq.Stale(gocb.Before)
+	}
+
+	rows, err := h.state.bucket.ExecuteViewQuery(vq)
+	if err != nil {
+		return err
+	}
+	slice := rv.Elem()
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	row := reflect.New(slice.Type().Elem())
+	for rows.Next(row.Interface()) {
+		result = reflect.Append(result, row.Elem())
+		row = reflect.New(slice.Type().Elem())
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	slice.Set(result)
+
+	return nil
+}
+
+// viewMap generates the map function emitting the field of the type's root documents, the soft
+// deleted and the binary documents aren't emitted, the parents of the field must be objects but
+// the field itself only has to be defined, so the zero values like 0, false and "" are emitted too
+func (h *Handler) viewMap(typ, field string) string {
+	prefix, _ := json.Marshal(h.state.getType(typ))
+	name, _ := json.Marshal(typ)
+
+	value := "doc"
+	var guards []string
+	for _, part := range strings.Split(field, ".") {
+		if value != "doc" {
+			guards = append(guards, fmt.Sprintf(`typeof %s === "object" && %s !== null`, value, value))
+		}
+		p, _ := json.Marshal(part)
+		value += "[" + string(p) + "]"
+	}
+	guards = append(guards, value+" !== undefined")
+
+	return fmt.Sprintf(`function (doc, meta) {
+  if (meta.type === "json" && meta.id.indexOf(%s) === 0 && doc._meta && doc._meta._type === %s && !doc._meta._deleted_at && %s) {
+    emit(%s, null);
+  }
+}`, prefix, name, strings.Join(guards, " && "), value)
+}
@@ -0,0 +1,133 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type viewWebshop struct {
+	Email          string   `json:"email" cb_view:"by_email"`
+	Status         string   `json:"status,omitempty" cb_view:"by_status"`
+	CardHolderName string   `json:"card_holder_name"`
+	Product        *product `json:"product" cb_referenced:"product"`
+}
+
+func TestViewsOf(t *testing.T) {
+	views, err := ViewsOf(&viewWebshop{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []View{
+		{Name: "by_email", Field: "email", Reduce: "_count"},
+		{Name: "by_status", Field: "status", Reduce: "_count"},
+	}, views)
+
+	type encrypted struct {
+		Name string `json:"name" cb_view:"by_name" cb_encrypted:"webshop_pii"`
+	}
+	_, err = ViewsOf(encrypted{})
+	assert.Equal(t, ErrEncryptedField, err)
+}
+
+func TestViewMap(t *testing.T) {
+	m := th.viewMap("webshop", "product.status")
+	assert.True(t, strings.Contains(m, `meta.id.indexOf("`+th.state.getType("webshop")+`") === 0`))
+	assert.True(t, strings.Contains(m, `typeof doc["product"] === "object" && doc["product"] !== null && doc["product"]["status"] !== undefined)`))
+	assert.True(t, strings.Contains(m, `emit(doc["product"]["status"], null)`))
+
+	m = th.viewMap("webshop", "final_grand_total")
+	assert.True(t, strings.Contains(m, `!doc._meta._deleted_at && doc["final_grand_total"] !== undefined)`))
+}
+
+type viewCounter struct {
+	Count int  `json:"count" cb_view:"by_count"`
+	Done  bool `json:"done" cb_view:"by_done"`
+}
+
+func TestViewsZeroValue(t *testing.T) {
+	ctx := context.Background()
+	_, id, err := th.Insert(ctx, "view_counter", "", viewCounter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := ViewsOf(&viewCounter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.SetViews(ctx, "view_counter", views); err != nil {
+		t.Fatal(err)
+	}
+	defer th.RemoveViews(ctx, "view_counter")
+
+	for name, key := range map[string]interface{}{"by_count": 0, "by_done": false} {
+		var rows []struct {
+			ID string `json:"id"`
+		}
+		q := ViewQuery{Key: key, Development: true}
+		if err := th.QueryView(WithConsistency(ctx, RequestPlus), "view_counter", name, q, &rows); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		assert.Contains(t, ids, th.state.getDocumentKey("view_counter", id), name)
+	}
+}
+
+func TestViews(t *testing.T) {
+	ctx := context.Background()
+	views, err := ViewsOf(&viewWebshop{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := th.SetViews(ctx, "webshop", views); err != nil {
+		t.Fatal(err)
+	}
+	defer th.RemoveViews(ctx, "webshop")
+
+	var rows []struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	q := ViewQuery{Key: "processed", Limit: 5, Development: true}
+	if err := th.QueryView(WithConsistency(ctx, RequestPlus), "webshop", "by_status", q, &rows); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, rows)
+	for _, row := range rows {
+		assert.Equal(t, "processed", row.Key)
+		assert.True(t, strings.HasPrefix(row.ID, th.state.getType("webshop")))
+	}
+
+	if err := th.PublishViews(ctx, "webshop"); err != nil {
+		t.Fatal(err)
+	}
+	var counts []struct {
+		Key   string `json:"key"`
+		Value int    `json:"value"`
+	}
+	q = ViewQuery{Group: true}
+	if err := th.QueryView(WithConsistency(ctx, RequestPlus), "webshop", "by_status", q, &counts); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, counts)
+
+	assert.Equal(t, ErrInvalidView, th.SetViews(ctx, "webshop", []View{{Name: "empty"}}))
+}
+
+func TestDesignDocumentNotFound(t *testing.T) {
+	assert.True(t, designDocumentNotFound(errors.New(`Message: {"error":"not_found","reason":"missing"}. Status Code: 404`)))
+	assert.False(t, designDocumentNotFound(errors.New("Status Code: 401")))
+	assert.False(t, designDocumentNotFound(errors.New("dial tcp: connection refused")))
+}
+
+func TestRemoveViewsMissing(t *testing.T) {
+	if err := th.RemoveViews(context.Background(), "missing_views"); err != nil {
+		t.Fatal(err)
+	}
+}